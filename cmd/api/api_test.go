@@ -0,0 +1,499 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"restapi/internal/auth"
+	"restapi/internal/handlers"
+	"restapi/internal/hasher"
+	"restapi/internal/models"
+	"restapi/internal/service"
+	"restapi/internal/storage"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// testAPI — полный router поверх хранилища в памяти
+type testAPI struct {
+	t       *testing.T
+	handler http.Handler
+	store   *storage.StorageMemory
+	// moderator публикует объявления, созданные в тестах
+	moderator string
+}
+
+func newTestAPI(t *testing.T) *testAPI {
+	t.Helper()
+	JWTKey = "test-key"
+	service.JWTKey = "test-key"
+	logger := zap.NewNop().Sugar()
+	store := storage.NewMemory()
+	passwordHasher, err := hasher.New(hasher.Bcrypt)
+	if err != nil {
+		t.Fatalf("hasher.New: %v", err)
+	}
+	svc := service.NewService(":0", "", logger, store, passwordHasher)
+	api := &testAPI{t: t, handler: newRouter(logger, store, svc, nil), store: store}
+
+	moderatorID := api.register("moderator")
+	if err := store.SetUserRole(context.Background(), moderatorID, auth.RoleModerator); err != nil {
+		t.Fatalf("SetUserRole: %v", err)
+	}
+	api.moderator = api.login("moderator")
+	return api
+}
+
+// withT возвращает копию для подтеста, чтобы ошибки помощников завершали именно его
+func (a *testAPI) withT(t *testing.T) *testAPI {
+	c := *a
+	c.t = t
+	return &c
+}
+
+// do выполняет запрос; token передается в Authorization как есть, body кодируется в JSON
+func (a *testAPI) do(method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	a.t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			a.t.Fatalf("encode body: %v", err)
+		}
+	}
+	req := httptest.NewRequest(method, "/api/v1"+path, &buf)
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	rec := httptest.NewRecorder()
+	a.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// decode читает JSON-ответ, предварительно проверив код
+func (a *testAPI) decode(rec *httptest.ResponseRecorder, status int, v interface{}) {
+	a.t.Helper()
+	if rec.Code != status {
+		a.t.Fatalf("status = %d, want %d, body: %s", rec.Code, status, rec.Body.String())
+	}
+	if v == nil {
+		return
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		a.t.Fatalf("decode response: %v, body: %s", err, rec.Body.String())
+	}
+}
+
+func (a *testAPI) register(login string) int {
+	a.t.Helper()
+	var resp struct {
+		UserID int `json:"user_id"`
+	}
+	a.decode(a.do("POST", "/register", "", handlers.RegisterRequest{Login: login, Password: "secret1"}), http.StatusCreated, &resp)
+	return resp.UserID
+}
+
+func (a *testAPI) login(login string) string {
+	a.t.Helper()
+	var resp handlers.LoginResponse
+	a.decode(a.do("POST", "/login", "", handlers.LoginRequest{Login: login, Password: "secret1"}), http.StatusOK, &resp)
+	return resp.Token
+}
+
+// publishAd создает объявление и одобряет его модератором, чтобы оно попало в ленту
+func (a *testAPI) publishAd(token, title string, price float64) int {
+	a.t.Helper()
+	var resp handlers.AdResponse
+	a.decode(a.do("POST", "/ads", token, adRequest(title, price)), http.StatusCreated, &resp)
+	a.decode(a.do("POST", fmt.Sprintf("/moderation/ads/%d/approve", resp.ID), a.moderator, nil), http.StatusNoContent, nil)
+	return resp.ID
+}
+
+func (a *testAPI) feed(query string) handlers.AdsPageResponse {
+	a.t.Helper()
+	var page handlers.AdsPageResponse
+	a.decode(a.do("GET", "/ads?"+query, "", nil), http.StatusOK, &page)
+	return page
+}
+
+func adRequest(title string, price float64) handlers.AdRequest {
+	return handlers.AdRequest{
+		Title:       title,
+		Description: "Description of " + title,
+		ImageURL:    "https://example.com/" + title + ".jpg",
+		Price:       price,
+	}
+}
+
+func adTitles(ads []models.Ad) []string {
+	titles := make([]string, 0, len(ads))
+	for _, ad := range ads {
+		titles = append(titles, ad.Title)
+	}
+	return titles
+}
+
+func TestRegisterUniqueLogin(t *testing.T) {
+	api := newTestAPI(t)
+	api.register("alice")
+
+	tests := []struct {
+		name   string
+		login  string
+		status int
+	}{
+		{"taken", "alice", http.StatusConflict},
+		{"new", "bob", http.StatusCreated},
+		{"too short", "al", http.StatusBadRequest},
+		{"invalid characters", "alice!", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := api.withT(t).do("POST", "/register", "", handlers.RegisterRequest{Login: tt.login, Password: "secret1"})
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body: %s", rec.Code, tt.status, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRegisterPasswordTooLongForBcrypt(t *testing.T) {
+	api := newTestAPI(t)
+	rec := api.do("POST", "/register", "", handlers.RegisterRequest{Login: "alice", Password: strings.Repeat("a", hasher.BcryptMaxPasswordBytes+1)})
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d, body: %s", rec.Code, http.StatusBadRequest, rec.Body.String())
+	}
+	api.decode(api.do("POST", "/register", "", handlers.RegisterRequest{Login: "alice", Password: strings.Repeat("a", hasher.BcryptMaxPasswordBytes)}), http.StatusCreated, nil)
+}
+
+func TestGetAdsFilterAndSort(t *testing.T) {
+	api := newTestAPI(t)
+	api.register("seller")
+	token := api.login("seller")
+	api.publishAd(token, "cheap", 100)
+	api.publishAd(token, "middle", 500)
+	api.publishAd(token, "pricey", 900)
+	// черновик не должен попадать в ленту ни при каких фильтрах
+	api.decode(api.do("POST", "/ads", token, handlers.AdRequest{
+		Title: "draft", Description: "Not published yet", ImageURL: "https://example.com/d.jpg", Price: 300, Draft: true,
+	}), http.StatusCreated, nil)
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"newest first by default", "", []string{"pricey", "middle", "cheap"}},
+		{"created_at ascending", "sort_by=created_at&sort_order=ASC", []string{"cheap", "middle", "pricey"}},
+		{"price descending", "sort_by=price&sort_order=DESC", []string{"pricey", "middle", "cheap"}},
+		{"price ascending", "sort_by=price&sort_order=ASC", []string{"cheap", "middle", "pricey"}},
+		{"min price", "min_price=500&sort_by=price&sort_order=ASC", []string{"middle", "pricey"}},
+		{"max price", "max_price=500&sort_by=price&sort_order=ASC", []string{"cheap", "middle"}},
+		{"price range", "min_price=200&max_price=800", []string{"middle"}},
+		{"empty range", "min_price=950", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := api.withT(t).feed(tt.query)
+			got := adTitles(page.Items)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("titles = %v, want %v", got, tt.want)
+			}
+			if page.Total != len(tt.want) {
+				t.Fatalf("total = %d, want %d", page.Total, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestGetAdsPagination(t *testing.T) {
+	api := newTestAPI(t)
+	api.register("seller")
+	token := api.login("seller")
+	for i := 1; i <= 5; i++ {
+		api.publishAd(token, fmt.Sprintf("ad%d", i), float64(i*100))
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		want       []string
+		totalPages int
+		hasNext    bool
+		hasPrev    bool
+	}{
+		{"first page", "page=1&page_size=2&sort_by=price&sort_order=ASC", []string{"ad1", "ad2"}, 3, true, false},
+		{"middle page", "page=2&page_size=2&sort_by=price&sort_order=ASC", []string{"ad3", "ad4"}, 3, true, true},
+		{"last page", "page=3&page_size=2&sort_by=price&sort_order=ASC", []string{"ad5"}, 3, false, true},
+		{"past the end", "page=4&page_size=2&sort_by=price&sort_order=ASC", []string{}, 3, false, true},
+		{"invalid page falls back to first", "page=abc&page_size=2&sort_by=price&sort_order=ASC", []string{"ad1", "ad2"}, 3, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := api.withT(t).feed(tt.query)
+			got := adTitles(page.Items)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("titles = %v, want %v", got, tt.want)
+			}
+			if page.Total != 5 || page.TotalPages != tt.totalPages {
+				t.Fatalf("total = %d, total_pages = %d, want 5 and %d", page.Total, page.TotalPages, tt.totalPages)
+			}
+			if (page.Links.Next != "") != tt.hasNext || (page.Links.Prev != "") != tt.hasPrev {
+				t.Fatalf("links = %+v, want next %v, prev %v", page.Links, tt.hasNext, tt.hasPrev)
+			}
+		})
+	}
+}
+
+func TestAdOwnership(t *testing.T) {
+	api := newTestAPI(t)
+	api.register("owner")
+	api.register("stranger")
+	owner := api.login("owner")
+	stranger := api.login("stranger")
+	adID := api.publishAd(owner, "bicycle", 300)
+	adPath := fmt.Sprintf("/ads/%d", adID)
+	title := "new title"
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   interface{}
+		status int
+	}{
+		{"put without token", "PUT", adPath, "", adRequest("bicycle", 250), http.StatusUnauthorized},
+		{"put by stranger", "PUT", adPath, stranger, adRequest("bicycle", 250), http.StatusForbidden},
+		{"patch by stranger", "PATCH", adPath, stranger, handlers.AdPatchRequest{Title: &title}, http.StatusForbidden},
+		{"delete by stranger", "DELETE", adPath, stranger, nil, http.StatusForbidden},
+		{"put missing ad", "PUT", "/ads/9999", owner, adRequest("bicycle", 250), http.StatusNotFound},
+		{"patch missing ad", "PATCH", "/ads/9999", owner, handlers.AdPatchRequest{Title: &title}, http.StatusNotFound},
+		{"delete missing ad", "DELETE", "/ads/9999", owner, nil, http.StatusNotFound},
+		{"put by owner", "PUT", adPath, owner, adRequest("bicycle", 250), http.StatusOK},
+		{"delete by owner", "DELETE", adPath, owner, nil, http.StatusNoContent},
+		{"get deleted ad", "GET", adPath, "", nil, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := api.withT(t).do(tt.method, tt.path, tt.token, tt.body)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body: %s", rec.Code, tt.status, rec.Body.String())
+			}
+		})
+	}
+}
+
+func (a *testAPI) priceDrops(token string) []models.Notification {
+	a.t.Helper()
+	var page handlers.NotificationsPageResponse
+	a.decode(a.do("GET", "/me/notifications", token, nil), http.StatusOK, &page)
+	var drops []models.Notification
+	for _, n := range page.Items {
+		if n.Type == models.NotificationPriceDrop {
+			drops = append(drops, n)
+		}
+	}
+	return drops
+}
+
+func TestPriceDropNotification(t *testing.T) {
+	tests := []struct {
+		name string
+		// update — PUT автора; title отличается от исходного, если меняется текст
+		update handlers.AdRequest
+		// approve — одобрить объявление после правки, если оно ушло на проверку
+		approve bool
+		want    []float64
+	}{
+		{"price lowered", adRequest("bicycle", 250), false, []float64{300, 250}},
+		{"price raised", adRequest("bicycle", 350), false, nil},
+		{"price unchanged", adRequest("bicycle", 300), false, nil},
+		{"price and title changed, before approval", adRequest("road bike", 250), false, nil},
+		{"price and title changed, after approval", adRequest("road bike", 250), true, []float64{300, 250}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newTestAPI(t)
+			api.register("seller")
+			api.register("buyer")
+			seller := api.login("seller")
+			buyer := api.login("buyer")
+			adID := api.publishAd(seller, "bicycle", 300)
+			api.decode(api.do("POST", fmt.Sprintf("/ads/%d/favorite", adID), buyer, nil), http.StatusNoContent, nil)
+
+			api.decode(api.do("PUT", fmt.Sprintf("/ads/%d", adID), seller, tt.update), http.StatusOK, nil)
+			if tt.approve {
+				api.decode(api.do("POST", fmt.Sprintf("/moderation/ads/%d/approve", adID), api.moderator, nil), http.StatusNoContent, nil)
+			}
+
+			drops := api.priceDrops(buyer)
+			if tt.want == nil {
+				if len(drops) != 0 {
+					t.Fatalf("notifications = %+v, want none", drops)
+				}
+				return
+			}
+			if len(drops) != 1 {
+				t.Fatalf("notifications = %+v, want one %s", drops, models.NotificationPriceDrop)
+			}
+			n := drops[0]
+			if n.AdID != adID || n.OldPrice == nil || n.NewPrice == nil || *n.OldPrice != tt.want[0] || *n.NewPrice != tt.want[1] {
+				t.Fatalf("notification = %+v, want ad %d price %v -> %v", n, adID, tt.want[0], tt.want[1])
+			}
+			// автор объявления о своей цене не уведомляется
+			if own := api.priceDrops(seller); len(own) != 0 {
+				t.Fatalf("seller notifications = %+v, want none", own)
+			}
+		})
+	}
+}
+
+func TestRequireRoleUsesCurrentUserState(t *testing.T) {
+	tests := []struct {
+		name string
+		// change меняет пользователя в хранилище после выдачи токена с ролью moderator
+		change func(ctx context.Context, store *storage.StorageMemory, userID int) error
+		status int
+	}{
+		{"unchanged", func(context.Context, *storage.StorageMemory, int) error { return nil }, http.StatusOK},
+		{"demoted", func(ctx context.Context, store *storage.StorageMemory, userID int) error {
+			return store.SetUserRole(ctx, userID, auth.RoleUser)
+		}, http.StatusForbidden},
+		{"banned", func(ctx context.Context, store *storage.StorageMemory, userID int) error {
+			return store.SetUserBanned(ctx, userID, true)
+		}, http.StatusForbidden},
+		{"promoted to admin", func(ctx context.Context, store *storage.StorageMemory, userID int) error {
+			return store.SetUserRole(ctx, userID, auth.RoleAdmin)
+		}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			api := newTestAPI(t)
+			userID := api.register("staff")
+			if err := api.store.SetUserRole(ctx, userID, auth.RoleModerator); err != nil {
+				t.Fatalf("SetUserRole: %v", err)
+			}
+			token := api.login("staff")
+			if err := tt.change(ctx, api.store, userID); err != nil {
+				t.Fatalf("change user: %v", err)
+			}
+			rec := api.do("GET", "/moderation/ads", token, nil)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body: %s", rec.Code, tt.status, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestAdminRoutesRequireCurrentAdmin(t *testing.T) {
+	ctx := context.Background()
+	api := newTestAPI(t)
+	userID := api.register("boss")
+	token := api.login("boss")
+	if rec := api.do("GET", "/admin/users", token, nil); rec.Code != http.StatusForbidden {
+		t.Fatalf("status before promotion = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	// новая роль действует сразу, без повторного входа
+	if err := api.store.SetUserRole(ctx, userID, auth.RoleAdmin); err != nil {
+		t.Fatalf("SetUserRole: %v", err)
+	}
+	api.decode(api.do("GET", "/admin/users", token, nil), http.StatusOK, nil)
+	// модератор не получает доступа к /admin
+	if rec := api.do("GET", "/admin/users", api.moderator, nil); rec.Code != http.StatusForbidden {
+		t.Fatalf("moderator status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestAuthUsesCurrentUserState(t *testing.T) {
+	ctx := context.Background()
+	api := newTestAPI(t)
+	sellerID := api.register("seller")
+	seller := api.login("seller")
+	var ad handlers.AdResponse
+	api.decode(api.do("POST", "/ads", seller, adRequest("Bike", 100)), http.StatusCreated, &ad)
+	path := fmt.Sprintf("/ads/%d", ad.ID)
+
+	staffID := api.register("staff")
+	if err := api.store.SetUserRole(ctx, staffID, auth.RoleModerator); err != nil {
+		t.Fatalf("SetUserRole: %v", err)
+	}
+	staff := api.login("staff")
+	api.decode(api.do("GET", path, staff, nil), http.StatusOK, nil)
+	// после понижения объявление на проверке скрыто, хотя в токене осталась роль moderator
+	if err := api.store.SetUserRole(ctx, staffID, auth.RoleUser); err != nil {
+		t.Fatalf("SetUserRole: %v", err)
+	}
+	if rec := api.do("GET", path, staff, nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("demoted moderator status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	// бан действует на все маршруты с токеном, не только на /moderation и /admin
+	if err := api.store.SetUserBanned(ctx, sellerID, true); err != nil {
+		t.Fatalf("SetUserBanned: %v", err)
+	}
+	for _, req := range []struct{ method, path string }{
+		{"POST", "/ads"},
+		{"GET", path},
+		{"GET", "/me/favorites"},
+	} {
+		if rec := api.do(req.method, req.path, seller, adRequest("Car", 500)); rec.Code != http.StatusForbidden {
+			t.Fatalf("%s %s status = %d, want %d", req.method, req.path, rec.Code, http.StatusForbidden)
+		}
+	}
+}
+
+func TestMetricsUseRouteTemplate(t *testing.T) {
+	api := newTestAPI(t)
+	api.register("seller")
+	adID := api.publishAd(api.login("seller"), "Bike", 100)
+
+	tests := []struct {
+		path  string
+		route string
+		code  int
+	}{
+		{fmt.Sprintf("/ads/%d", adID), "/api/v1/ads/{id:[0-9]+}", http.StatusOK},
+		{"/no-such-route", "unmatched", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		before := requestsTotal(t, "GET", tt.route, tt.code)
+		if rec := api.do("GET", tt.path, "", nil); rec.Code != tt.code {
+			t.Fatalf("GET %s status = %d, want %d", tt.path, rec.Code, tt.code)
+		}
+		if got := requestsTotal(t, "GET", tt.route, tt.code) - before; got != 1 {
+			t.Fatalf("GET %s counted %v times under route %q, want 1", tt.path, got, tt.route)
+		}
+	}
+}
+
+// requestsTotal возвращает текущее значение http_requests_total для набора меток (0, если запросов еще не было)
+func requestsTotal(t *testing.T, method, route string, code int) float64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("gather metrics: %v", err)
+	}
+	want := map[string]string{"method": method, "route": route, "status": fmt.Sprint(code)}
+	for _, family := range families {
+		if !strings.HasSuffix(family.GetName(), "_http_requests_total") {
+			continue
+		}
+	series:
+		for _, m := range family.GetMetric() {
+			for _, label := range m.GetLabel() {
+				if want[label.GetName()] != label.GetValue() {
+					continue series
+				}
+			}
+			return m.GetCounter().GetValue()
+		}
+	}
+	return 0
+}
@@ -4,15 +4,21 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"restapi/internal/blobstore"
 	"restapi/internal/config"
-	"restapi/internal/handlers"
+	"restapi/internal/events"
+	"restapi/internal/hasher"
 	"restapi/internal/logger"
-	"restapi/internal/middleware"
+	"restapi/internal/metrics"
+	"restapi/internal/migrate"
 	"restapi/internal/service"
 	"restapi/internal/storage"
+	"syscall"
+	"time"
 
-	"github.com/gorilla/mux"
 	_ "github.com/jackc/pgx/v5/stdlib"
+	"go.uber.org/zap"
 )
 
 var JWTKey = os.Getenv("JWT_KEY")
@@ -21,49 +27,129 @@ func main() {
 	JWTKey = string(JWTKey)
 	if JWTKey == "" {
 		fmt.Println("JWT_KEY environment variable is not set")
-		return
+		os.Exit(1)
 	}
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		fmt.Errorf("error loading config: %v", err)
+		fmt.Printf("error loading config: %v\n", err)
+		os.Exit(1)
 	}
 	fmt.Printf("Config loaded\n")
 
 	logger, err := logger.NewLogger(cfg.Logger.Level)
 	if err != nil {
-		fmt.Errorf("error creating logger: %v", err)
+		fmt.Printf("error creating logger: %v\n", err)
+		os.Exit(1)
 	}
 	fmt.Printf("Logger created with level: %s\n", logger.Level())
 
-	if err != nil {
-		logger.Errorf("error connecting to database: %v", err)
+	var store storage.Storage
+	var migrator *migrate.Migrator
+	// fatal завершает процесс с кодом 1, чтобы скрипты деплоя увидели ошибку; os.Exit не выполняет defer, поэтому хранилище закрывается здесь
+	fatalLogger := logger.WithOptions(zap.AddCallerSkip(1))
+	fatal := func(format string, args ...interface{}) {
+		fatalLogger.Errorf(format, args...)
+		if store != nil {
+			store.Close()
+		}
+		os.Exit(1)
+	}
+	switch cfg.Database.Driver {
+	case "memory":
+		logger.Infof("Using in-memory storage")
+		store = storage.NewMemory()
+	default:
+		pg := storage.New(context.Background(), cfg.Database.Port, cfg.Database.Username, cfg.Database.Host, cfg.Database.Name, cfg.Database.Password)
+		if pg == nil {
+			fatal("error connecting to database")
+		}
+		store = pg
+		metrics.RegisterDBStats(pg.Database, cfg.Database.Name)
+		migrator, err = migrate.New(pg.Database, logger)
+		if err != nil {
+			fatal("error loading migrations: %v", err)
+		}
+	}
+	defer store.Close()
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if migrator == nil {
+			fatal("migrations are only supported for the postgres driver")
+		}
+		if err := runMigrate(context.Background(), migrator, logger, os.Args[2:]); err != nil {
+			fatal("migrate: %v", err)
+		}
 		return
 	}
-	storage := storage.New(context.Background(), cfg.Database.Port, cfg.Database.Username, cfg.Database.Host, cfg.Database.Name, cfg.Database.Password)
-	if storage == nil {
-		logger.Errorf("error creating storage: %v", err)
+	if len(os.Args) > 1 && os.Args[1] == "set-role" {
+		if err := runSetRole(context.Background(), store, logger, os.Args[2:]); err != nil {
+			fatal("set-role: %v", err)
+		}
 		return
 	}
-	defer storage.Database.Close()
+	if migrator != nil && cfg.Database.MigrateOnStart {
+		if err := migrator.Up(context.Background()); err != nil {
+			fatal("error applying migrations: %v", err)
+		}
+	}
 
-	svc := service.NewService(cfg.Server.Port, cfg.Server.Host, logger, storage)
+	passwordHasher, err := hasher.New(cfg.Auth.PasswordHasher)
+	if err != nil {
+		fatal("error creating password hasher: %v", err)
+	}
 
-	r := mux.NewRouter()
+	svc := service.NewService(cfg.Server.Port, cfg.Server.Host, logger, store, passwordHasher)
+	if cfg.Auth.AccessTokenTTL > 0 {
+		svc.AccessTokenTTL = cfg.Auth.AccessTokenTTL
+	}
+	if cfg.Auth.RefreshTokenTTL > 0 {
+		svc.RefreshTokenTTL = cfg.Auth.RefreshTokenTTL
+	}
+	if cfg.Media.MaxUploadSize > 0 {
+		svc.MaxImageSize = cfg.Media.MaxUploadSize
+	}
+	blobs, err := blobstore.New(cfg.Media.Driver, cfg.Media.Dir, cfg.Media.PublicURL)
+	if err != nil {
+		fatal("error creating media storage: %v", err)
+	}
+	svc.Blobs = blobs
+	svc.Events = events.NewHub(64)
+	svc.StartMatcher(256)
 
-	h := handlers.NewHandler(svc)
-	//не нужен jwt token
-	public := r.PathPrefix("/api/v1").Subrouter()
-	public.HandleFunc("/register", h.RegisterHandler).Methods("POST")
-	public.HandleFunc("/login", h.LoginHandler).Methods("POST")
-	public.HandleFunc("/ads", h.GetAdsHandler).Methods("GET")
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	//нужен jwt token
-	protected := r.PathPrefix("/api/v1").Subrouter()
-	protected.Use(middleware.AuthMiddleware(logger, JWTKey))
-	protected.HandleFunc("/ads", h.CreateAdHandler).Methods("POST")
+	handler := newRouter(logger, store, svc, blobs)
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- svc.ListenAndServe(handler)
+	}()
 
-	if err := svc.ListenAndServe(r); err != nil {
-		logger.Errorf("error starting server: %v", err)
-		os.Exit(1)
+	select {
+	case err := <-serverErr:
+		if err != nil {
+			fatal("error starting server: %v", err)
+		}
+		return
+	case <-ctx.Done():
+	}
+	stop()
+
+	// сначала /readyz отвечает 503, чтобы балансировщик успел убрать инстанс, и только потом закрываем listener
+	svc.StartDraining()
+	if cfg.Server.ShutdownDelay > 0 {
+		logger.Infof("Shutdown signal received, marked as not ready for %s", cfg.Server.ShutdownDelay)
+		time.Sleep(cfg.Server.ShutdownDelay)
+	}
+	logger.Infof("Waiting up to %s for in-flight requests", cfg.Server.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := svc.Shutdown(shutdownCtx); err != nil {
+		logger.Errorf("error draining connections: %v", err)
+	}
+	if err := <-serverErr; err != nil {
+		logger.Errorf("server error: %v", err)
 	}
+	// хранилище закрывается отложенным store.Close() только после того, как все запросы завершены
+	logger.Infof("Server stopped")
 }
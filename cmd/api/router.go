@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net/http"
+	"restapi/internal/auth"
+	"restapi/internal/blobstore"
+	"restapi/internal/handlers"
+	"restapi/internal/metrics"
+	"restapi/internal/middleware"
+	"restapi/internal/service"
+	"restapi/internal/storage"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// newRouter собирает все маршруты API вместе с access log и метриками
+func newRouter(logger *zap.SugaredLogger, store storage.Storage, svc *service.Service, blobs blobstore.Store) http.Handler {
+	r := mux.NewRouter()
+	r.Handle("/metrics", metrics.Handler()).Methods("GET")
+
+	h := handlers.NewHandler(svc)
+	r.HandleFunc("/healthz", h.HealthzHandler).Methods("GET")
+	r.HandleFunc("/readyz", h.ReadyzHandler).Methods("GET")
+	if local, ok := blobs.(*blobstore.Local); ok {
+		r.PathPrefix("/media/").Handler(handlers.MediaHandler(local.Dir())).Methods("GET", "HEAD")
+	}
+
+	//не нужен jwt token
+	public := r.PathPrefix("/api/v1").Subrouter()
+	public.HandleFunc("/register", h.RegisterHandler).Methods("POST")
+	public.HandleFunc("/login", h.LoginHandler).Methods("POST")
+	public.HandleFunc("/auth/refresh", h.RefreshHandler).Methods("POST")
+	public.HandleFunc("/categories", h.CategoriesHandler).Methods("GET")
+
+	//jwt token необязателен: с ним в ответе заполняются персональные поля (is_owner)
+	optional := r.PathPrefix("/api/v1").Subrouter()
+	optional.Use(middleware.OptionalAuthMiddleware(logger, JWTKey, store))
+	optional.HandleFunc("/ads", h.GetAdsHandler).Methods("GET")
+	optional.HandleFunc("/ads/{id:[0-9]+}", h.GetAdHandler).Methods("GET")
+	optional.HandleFunc("/ads/{id:[0-9]+}/price-history", h.PriceHistoryHandler).Methods("GET")
+
+	//нужен jwt token
+	protected := r.PathPrefix("/api/v1").Subrouter()
+	protected.Use(middleware.AuthMiddleware(logger, JWTKey, store))
+	protected.HandleFunc("/auth/logout", h.LogoutHandler).Methods("POST")
+	protected.HandleFunc("/events", h.EventsHandler).Methods("GET")
+	protected.HandleFunc("/ads", h.CreateAdHandler).Methods("POST")
+	protected.HandleFunc("/images", h.UploadImageHandler).Methods("POST")
+	protected.HandleFunc("/ads/{id:[0-9]+}", h.UpdateAdHandler).Methods("PUT")
+	protected.HandleFunc("/ads/{id:[0-9]+}", h.PatchAdHandler).Methods("PATCH")
+	protected.HandleFunc("/ads/{id:[0-9]+}", h.DeleteAdHandler).Methods("DELETE")
+	protected.HandleFunc("/ads/{id:[0-9]+}/submit", h.SubmitAdHandler).Methods("POST")
+	protected.HandleFunc("/ads/{id:[0-9]+}/archive", h.ArchiveAdHandler).Methods("POST")
+	protected.HandleFunc("/ads/{id:[0-9]+}/images", h.AddAdImageHandler).Methods("POST")
+	protected.HandleFunc("/ads/{id:[0-9]+}/images/order", h.ReorderAdImagesHandler).Methods("PUT")
+	protected.HandleFunc("/ads/{id:[0-9]+}/images/{imageID:[0-9]+}", h.DeleteAdImageHandler).Methods("DELETE")
+	protected.HandleFunc("/ads/{id:[0-9]+}/favorite", h.AddFavoriteHandler).Methods("POST")
+	protected.HandleFunc("/ads/{id:[0-9]+}/favorite", h.RemoveFavoriteHandler).Methods("DELETE")
+	protected.HandleFunc("/me/favorites", h.MyFavoritesHandler).Methods("GET")
+	protected.HandleFunc("/me/searches", h.ListSavedSearchesHandler).Methods("GET")
+	protected.HandleFunc("/me/searches", h.CreateSavedSearchHandler).Methods("POST")
+	protected.HandleFunc("/me/searches/{id:[0-9]+}", h.DeleteSavedSearchHandler).Methods("DELETE")
+	protected.HandleFunc("/me/notifications", h.NotificationsHandler).Methods("GET")
+	protected.HandleFunc("/me/notifications/read", h.MarkAllNotificationsReadHandler).Methods("POST")
+	protected.HandleFunc("/me/notifications/{id:[0-9]+}/read", h.MarkNotificationReadHandler).Methods("POST")
+	protected.HandleFunc("/ads/{id:[0-9]+}/threads", h.StartThreadHandler).Methods("POST")
+	protected.HandleFunc("/threads", h.ListThreadsHandler).Methods("GET")
+	protected.HandleFunc("/threads/{id:[0-9]+}/messages", h.ThreadMessagesHandler).Methods("GET")
+	protected.HandleFunc("/threads/{id:[0-9]+}/messages", h.SendMessageHandler).Methods("POST")
+	protected.HandleFunc("/threads/{id:[0-9]+}/read", h.MarkThreadReadHandler).Methods("POST")
+
+	//нужен jwt token с ролью moderator или admin
+	moderation := r.PathPrefix("/api/v1/moderation").Subrouter()
+	moderation.Use(middleware.AuthMiddleware(logger, JWTKey, store), middleware.RequireRole(auth.RoleModerator, auth.RoleAdmin))
+	moderation.HandleFunc("/ads", h.ModerationQueueHandler).Methods("GET")
+	moderation.HandleFunc("/ads/{id:[0-9]+}/approve", h.ApproveAdHandler).Methods("POST")
+	moderation.HandleFunc("/ads/{id:[0-9]+}/reject", h.RejectAdHandler).Methods("POST")
+
+	//нужен jwt token с ролью admin
+	admin := r.PathPrefix("/api/v1/admin").Subrouter()
+	admin.Use(middleware.AuthMiddleware(logger, JWTKey, store), middleware.RequireRole(auth.RoleAdmin))
+	admin.HandleFunc("/users", h.ListUsersHandler).Methods("GET")
+	admin.HandleFunc("/users/{id:[0-9]+}/role", h.SetUserRoleHandler).Methods("PUT")
+	admin.HandleFunc("/users/{id:[0-9]+}/ban", h.BanUserHandler).Methods("POST")
+	admin.HandleFunc("/users/{id:[0-9]+}/ban", h.UnbanUserHandler).Methods("DELETE")
+	admin.HandleFunc("/ads/{id:[0-9]+}", h.RemoveAdHandler).Methods("DELETE")
+
+	// access log и метрики оборачивают router снаружи: middleware из r.Use выполняются только для найденных маршрутов
+	return middleware.RequestLogger(logger, r)(middleware.MetricsMiddleware(r)(r))
+}
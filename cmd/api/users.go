@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"restapi/internal/auth"
+	"restapi/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+// runSetRole выполняет подкоманду set-role <login> <role>: так назначается первый администратор
+func runSetRole(ctx context.Context, store storage.Storage, logger *zap.SugaredLogger, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: set-role <login> <user|moderator|admin>")
+	}
+	login, role := args[0], args[1]
+	if !auth.ValidRole(role) {
+		return fmt.Errorf("unknown role: %s", role)
+	}
+	u, err := store.GetUserByLogin(ctx, login)
+	if err != nil {
+		return err
+	}
+	if err := store.SetUserRole(ctx, u.ID, role); err != nil {
+		return err
+	}
+	logger.Infof("User %s (ID %d) now has role %s", login, u.ID, role)
+	return nil
+}
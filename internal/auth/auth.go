@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"context"
+	"time"
+)
+
+// Способы аутентификации, которыми может быть получен Principal
+const (
+	MethodJWT = "jwt"
+)
+
+// Роли пользователей
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// ValidRole сообщает, что role — одна из известных ролей
+func ValidRole(role string) bool {
+	switch role {
+	case RoleUser, RoleModerator, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+// Principal — аутентифицированный пользователь текущего запроса
+type Principal struct {
+	UserID int
+	Login  string
+	Roles  []string
+	// TokenID и ExpiresAt — jti и срок действия токена, по которому пришел запрос (для отзыва при выходе)
+	TokenID   string
+	ExpiresAt time.Time
+	// Method — способ аутентификации (MethodJWT)
+	Method string
+}
+
+// HasRole сообщает, что у пользователя есть хотя бы одна из ролей
+func (p Principal) HasRole(roles ...string) bool {
+	for _, have := range p.Roles {
+		for _, want := range roles {
+			if have == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type contextKey struct{}
+
+// WithPrincipal возвращает контекст с пользователем запроса
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext возвращает пользователя запроса; ok == false для анонимного запроса
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(contextKey{}).(Principal)
+	return p, ok
+}
+
+// UserID возвращает ID пользователя запроса или 0 для анонимного
+func UserID(ctx context.Context) int {
+	p, _ := FromContext(ctx)
+	return p.UserID
+}
@@ -0,0 +1,30 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrNotFound возвращается для отсутствующего объекта
+var ErrNotFound = errors.New("blob not found")
+
+// Store хранит загруженные файлы по ключу вида "ab/cdef0123.jpg".
+// Реализации: локальная файловая система; S3-совместимое хранилище подключается через этот же интерфейс
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	Delete(ctx context.Context, key string) error
+	// URL возвращает публичный адрес объекта
+	URL(key string) string
+}
+
+// New создает хранилище по имени драйвера из конфига
+func New(driver, dir, publicURL string) (Store, error) {
+	switch driver {
+	case "", "local":
+		return NewLocal(dir, publicURL)
+	default:
+		return nil, fmt.Errorf("unsupported blob store driver: %s", driver)
+	}
+}
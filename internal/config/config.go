@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -10,11 +11,17 @@ type Config struct {
 	Server   configServer   `mapstructure:"server" json:"server"`
 	Database configDatabase `mapstructure:"database" json:"database"`
 	Logger   configLogger   `mapstructure:"logger" json:"logger"`
+	Auth     configAuth     `mapstructure:"auth" json:"auth"`
+	Media    configMedia    `mapstructure:"media" json:"media"`
 }
 
 type configServer struct {
 	Port string `mapstructure:"port" json:"port"`
 	Host string `mapstructure:"host" json:"host"`
+	// ShutdownTimeout — сколько ждать завершения текущих запросов после SIGINT/SIGTERM
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout" json:"shutdown_timeout"`
+	// ShutdownDelay — сколько /readyz отвечает 503 до закрытия listener'а
+	ShutdownDelay time.Duration `mapstructure:"shutdown_delay" json:"shutdown_delay"`
 }
 
 type configDatabase struct {
@@ -24,17 +31,39 @@ type configDatabase struct {
 	Username string `mapstructure:"username" json:"username"`
 	Password string `mapstructure:"password" json:"password"`
 	Name     string `mapstructure:"name" json:"name"`
+	// MigrateOnStart применяет миграции при запуске сервера
+	MigrateOnStart bool `mapstructure:"migrate_on_start" json:"migrate_on_start"`
 }
 
 type configLogger struct {
 	Level string `mapstructure:"level" json:"level"`
 }
 
+type configAuth struct {
+	PasswordHasher  string        `mapstructure:"password_hasher" json:"password_hasher"`
+	AccessTokenTTL  time.Duration `mapstructure:"access_token_ttl" json:"access_token_ttl"`
+	RefreshTokenTTL time.Duration `mapstructure:"refresh_token_ttl" json:"refresh_token_ttl"`
+}
+
+type configMedia struct {
+	// Driver — хранилище загруженных изображений: "local" (каталог Dir)
+	Driver string `mapstructure:"driver" json:"driver"`
+	Dir    string `mapstructure:"dir" json:"dir"`
+	// PublicURL — адрес, по которому клиенты получают файлы; для local раздается по пути /media/
+	PublicURL     string `mapstructure:"public_url" json:"public_url"`
+	MaxUploadSize int64  `mapstructure:"max_upload_size" json:"max_upload_size"`
+}
+
 func LoadConfig() (*Config, error) {
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
 	viper.AddConfigPath(".")
 	viper.AutomaticEnv()
+	viper.SetDefault("server.shutdown_timeout", "15s")
+	viper.SetDefault("media.driver", "local")
+	viper.SetDefault("media.dir", "./uploads")
+	viper.SetDefault("media.public_url", "http://localhost:8080/media")
+	viper.SetDefault("media.max_upload_size", 5<<20)
 
 	if err := viper.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
@@ -0,0 +1,129 @@
+package events
+
+import (
+	"restapi/internal/models"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// Типы событий
+const (
+	AdCreated      = "ad.created"
+	AdUpdated      = "ad.updated"
+	AdUnpublished  = "ad.unpublished"
+	AdDeleted      = "ad.deleted"
+	MessageCreated = "message.created"
+	// NotificationCreated отправляется только получателю уведомления
+	NotificationCreated = "notification.created"
+)
+
+// Event — событие для подписчиков; ID присваивает Hub при публикации
+type Event struct {
+	ID       uint64
+	Type     string
+	Data     any
+	Audience Audience
+}
+
+// Audience задает получателей события: всех, перечисленных пользователей и/или модераторов
+type Audience struct {
+	All        bool
+	UserIDs    []int
+	Moderators bool
+}
+
+// Includes сообщает, должен ли viewer получить событие
+func (a Audience) Includes(viewer models.Viewer) bool {
+	return a.All ||
+		(viewer.UserID != 0 && slices.Contains(a.UserIDs, viewer.UserID)) ||
+		(a.Moderators && viewer.CanModerate)
+}
+
+type subscription struct {
+	viewer models.Viewer
+	ch     chan Event
+}
+
+// Hub — pub/sub внутри процесса: сервис публикует события, SSE-подключения их получают.
+// Медленный подписчик, у которого переполнился буфер, отключается: клиент переподключится и перечитает состояние
+type Hub struct {
+	mu         sync.Mutex
+	subs       map[*subscription]struct{}
+	closed     bool
+	bufferSize int
+	lastID     atomic.Uint64
+}
+
+// NewHub создает Hub с буфером bufferSize событий на подписчика
+func NewHub(bufferSize int) *Hub {
+	if bufferSize < 1 {
+		bufferSize = 64
+	}
+	return &Hub{subs: make(map[*subscription]struct{}), bufferSize: bufferSize}
+}
+
+// Subscribe подписывает viewer на события. Канал закрывается при отписке, переполнении буфера или Close
+func (h *Hub) Subscribe(viewer models.Viewer) (<-chan Event, func()) {
+	sub := &subscription{viewer: viewer, ch: make(chan Event, h.bufferSize)}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(sub.ch)
+		return sub.ch, func() {}
+	}
+	h.subs[sub] = struct{}{}
+	return sub.ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.remove(sub)
+	}
+}
+
+// Publish рассылает событие подписчикам из его аудитории, не блокируясь на медленных
+func (h *Hub) Publish(ev Event) {
+	ev.ID = h.lastID.Add(1)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs {
+		if !ev.Audience.Includes(sub.viewer) {
+			continue
+		}
+		select {
+		case sub.ch <- ev:
+		default:
+			h.remove(sub)
+		}
+	}
+}
+
+// Subscribers возвращает число активных подписчиков
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs)
+}
+
+// Close отключает всех подписчиков; вызывается при остановке сервера, чтобы SSE-запросы завершились
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for sub := range h.subs {
+		h.remove(sub)
+	}
+}
+
+// remove закрывает канал подписчика; вызывается под h.mu
+func (h *Hub) remove(sub *subscription) {
+	if _, ok := h.subs[sub]; !ok {
+		return
+	}
+	delete(h.subs, sub)
+	close(sub.ch)
+}
+
+// AdRef — данные событий, в которых достаточно ID объявления (ad.deleted, ad.unpublished)
+type AdRef struct {
+	ID int `json:"id"`
+}
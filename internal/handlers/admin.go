@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"restapi/internal/auth"
+	"restapi/internal/service"
+	"restapi/internal/storage"
+	"restapi/internal/user"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// RoleRequest — тело запроса смены роли
+type RoleRequest struct {
+	Role string `json:"role"`
+}
+
+// UsersPageResponse — страница списка пользователей
+type UsersPageResponse struct {
+	Items      []user.User `json:"items"`
+	Page       int         `json:"page"`
+	PageSize   int         `json:"page_size"`
+	Total      int         `json:"total"`
+	TotalPages int         `json:"total_pages"`
+}
+
+// ListUsersHandler возвращает список пользователей (только для администраторов)
+func (h *Handler) ListUsersHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(defaultTimeForCancel)*time.Second)
+	defer cancel()
+	users, err := h.svc.ListUsers(ctx, page, pageSize)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to list users"})
+		return
+	}
+	items := users.Items
+	if items == nil {
+		items = []user.User{}
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(UsersPageResponse{
+		Items:      items,
+		Page:       users.Page,
+		PageSize:   users.PageSize,
+		Total:      users.Total,
+		TotalPages: (users.Total + users.PageSize - 1) / users.PageSize,
+	})
+}
+
+// SetUserRoleHandler меняет роль пользователя
+func (h *Handler) SetUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userID, ok := userIDFromRequest(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid user ID"})
+		return
+	}
+	var req RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(defaultTimeForCancel)*time.Second)
+	defer cancel()
+	if err := h.svc.SetUserRole(ctx, auth.UserID(r.Context()), userID, req.Role); err != nil {
+		writeUserError(w, err, "Failed to set user role")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// BanUserHandler блокирует пользователя
+func (h *Handler) BanUserHandler(w http.ResponseWriter, r *http.Request) {
+	h.setUserBanned(w, r, true)
+}
+
+// UnbanUserHandler снимает блокировку пользователя
+func (h *Handler) UnbanUserHandler(w http.ResponseWriter, r *http.Request) {
+	h.setUserBanned(w, r, false)
+}
+
+func (h *Handler) setUserBanned(w http.ResponseWriter, r *http.Request, banned bool) {
+	w.Header().Set("Content-Type", "application/json")
+	userID, ok := userIDFromRequest(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid user ID"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(defaultTimeForCancel)*time.Second)
+	defer cancel()
+	var err error
+	if banned {
+		err = h.svc.BanUser(ctx, auth.UserID(r.Context()), userID)
+	} else {
+		err = h.svc.UnbanUser(ctx, auth.UserID(r.Context()), userID)
+	}
+	if err != nil {
+		writeUserError(w, err, "Failed to update user ban")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemoveAdHandler удаляет любое объявление
+func (h *Handler) RemoveAdHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	adID, ok := adIDFromRequest(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid ad ID"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(defaultTimeForCancel)*time.Second)
+	defer cancel()
+	if err := h.svc.RemoveAd(ctx, auth.UserID(r.Context()), adID); err != nil {
+		writeAdError(w, err, "Failed to remove ad")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func userIDFromRequest(r *http.Request) (int, bool) {
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || userID <= 0 {
+		return 0, false
+	}
+	return userID, true
+}
+
+// writeUserError отвечает 404/400 для известных ошибок и 500 для остальных
+func writeUserError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, storage.ErrUserNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User not found"})
+	case errors.Is(err, service.ErrInvalidRole):
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Role must be one of: user, moderator, admin"})
+	case errors.Is(err, service.ErrSelfAction):
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Cannot ban or demote your own account"})
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: fallback})
+	}
+}
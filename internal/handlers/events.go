@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"restapi/internal/auth"
+	"restapi/internal/events"
+	"restapi/internal/metrics"
+	"time"
+)
+
+// eventsHeartbeat — период комментариев-пингов, чтобы прокси не закрывали простаивающий поток
+var eventsHeartbeat = 25 * time.Second
+
+// EventsHandler отдает поток Server-Sent Events: изменения видимых пользователю объявлений и новые сообщения.
+// Поток закрывается, когда истекает access-токен: клиент переподключается со свежим токеном
+func (h *Handler) EventsHandler(w http.ResponseWriter, r *http.Request) {
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User not authenticated"})
+		return
+	}
+	if h.svc.Events == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Event stream is disabled"})
+		return
+	}
+	rc := http.NewResponseController(w)
+	// WriteTimeout сервера рассчитан на обычные запросы и оборвал бы поток
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Streaming is not supported"})
+		return
+	}
+
+	ch, unsubscribe := h.svc.Events.Subscribe(viewerFromRequest(r))
+	defer unsubscribe()
+	metrics.EventStreams.Inc()
+	defer metrics.EventStreams.Dec()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 3000\n\n")
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+	var expired <-chan time.Time
+	if !principal.ExpiresAt.IsZero() {
+		timer := time.NewTimer(time.Until(principal.ExpiresAt))
+		defer timer.Stop()
+		expired = timer.C
+	}
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-expired:
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case ev, ok := <-ch:
+			if !ok {
+				// отписаны хабом: переполнение буфера или остановка сервера
+				return
+			}
+			if err := writeEvent(w, ev); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeEvent пишет событие в формате SSE: id, тип и JSON-данные одной строкой
+func writeEvent(w http.ResponseWriter, ev events.Event) error {
+	data, err := json.Marshal(ev.Data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, data)
+	return err
+}
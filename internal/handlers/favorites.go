@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"restapi/internal/auth"
+	"restapi/internal/models"
+	"time"
+)
+
+// FavoriteRequest — необязательное тело запроса добавления в избранное
+type FavoriteRequest struct {
+	// PriceThreshold — уведомлять о снижении цены, только когда она не выше порога
+	PriceThreshold *float64 `json:"price_threshold"`
+}
+
+// AddFavoriteHandler добавляет объявление в избранное; повторный запрос обновляет порог цены
+func (h *Handler) AddFavoriteHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User not authenticated"})
+		return
+	}
+	adID, ok := adIDFromRequest(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid ad ID"})
+		return
+	}
+	var req FavoriteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+	if req.PriceThreshold != nil && *req.PriceThreshold < 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Price threshold must not be negative"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(defaultTimeForCancel)*time.Second)
+	defer cancel()
+	if err := h.svc.AddFavorite(ctx, principal.UserID, adID, req.PriceThreshold); err != nil {
+		writeAdError(w, err, "Failed to add favorite")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemoveFavoriteHandler убирает объявление из избранного; отсутствие в избранном не ошибка
+func (h *Handler) RemoveFavoriteHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User not authenticated"})
+		return
+	}
+	adID, ok := adIDFromRequest(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid ad ID"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(defaultTimeForCancel)*time.Second)
+	defer cancel()
+	if err := h.svc.RemoveFavorite(ctx, principal.UserID, adID); err != nil {
+		writeAdError(w, err, "Failed to remove favorite")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MyFavoritesHandler возвращает избранные объявления пользователя с теми же фильтрами и пагинацией, что и лента
+func (h *Handler) MyFavoritesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User not authenticated"})
+		return
+	}
+	filter, msg := adsFilterFromRequest(r)
+	if msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: msg})
+		return
+	}
+	filter.FavoritesOf = principal.UserID
+	h.writeAdsPage(w, r, filter)
+}
+
+// PriceHistoryHandler возвращает историю цены объявления от старых записей к новым
+func (h *Handler) PriceHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	adID, ok := adIDFromRequest(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid ad ID"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(defaultTimeForCancel)*time.Second)
+	defer cancel()
+	history, err := h.svc.PriceHistory(ctx, adID, viewerFromRequest(r))
+	if err != nil {
+		writeAdError(w, err, "Failed to get price history")
+		return
+	}
+	if history == nil {
+		history = []models.PricePoint{}
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(history)
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"restapi/internal/auth"
+	"restapi/internal/service"
+	"restapi/internal/storage"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// AdImageRequest — тело запроса добавления изображения в галерею
+type AdImageRequest struct {
+	URL string `json:"url"`
+}
+
+// ImageOrderRequest — новый порядок галереи: ID всех изображений объявления
+type ImageOrderRequest struct {
+	ImageIDs []int `json:"image_ids"`
+}
+
+// AddAdImageHandler добавляет изображение в конец галереи объявления автора
+func (h *Handler) AddAdImageHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User not authenticated"})
+		return
+	}
+	adID, ok := adIDFromRequest(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid ad ID"})
+		return
+	}
+	var req AdImageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+	if msg := validateImageURL(req.URL); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: msg})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(defaultTimeForCancel)*time.Second)
+	defer cancel()
+	img, err := h.svc.AddAdImage(ctx, principal.UserID, adID, req.URL)
+	if err != nil {
+		writeAdImageError(w, err, "Failed to add image")
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(img)
+}
+
+// DeleteAdImageHandler удаляет изображение из галереи объявления автора
+func (h *Handler) DeleteAdImageHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User not authenticated"})
+		return
+	}
+	adID, ok := adIDFromRequest(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid ad ID"})
+		return
+	}
+	imageID, err := strconv.Atoi(mux.Vars(r)["imageID"])
+	if err != nil || imageID <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid image ID"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(defaultTimeForCancel)*time.Second)
+	defer cancel()
+	if err := h.svc.RemoveAdImage(ctx, principal.UserID, adID, imageID); err != nil {
+		writeAdImageError(w, err, "Failed to delete image")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ReorderAdImagesHandler задает порядок галереи; первое изображение становится обложкой
+func (h *Handler) ReorderAdImagesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User not authenticated"})
+		return
+	}
+	adID, ok := adIDFromRequest(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid ad ID"})
+		return
+	}
+	var req ImageOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(defaultTimeForCancel)*time.Second)
+	defer cancel()
+	images, err := h.svc.ReorderAdImages(ctx, principal.UserID, adID, req.ImageIDs)
+	if err != nil {
+		writeAdImageError(w, err, "Failed to reorder images")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(images)
+}
+
+// writeAdImageError дополняет writeAdError ошибками галереи
+func writeAdImageError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, storage.ErrAdImageNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Image not found"})
+	case errors.Is(err, service.ErrInvalidImageOrder):
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "image_ids must list every image of the ad exactly once"})
+	case errors.Is(err, service.ErrTooManyImages):
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Ad already has the maximum number of images"})
+	case errors.Is(err, service.ErrLastImage):
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "An ad must have at least one image"})
+	default:
+		writeAdError(w, err, fallback)
+	}
+}
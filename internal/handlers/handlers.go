@@ -3,12 +3,20 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"regexp"
+	"restapi/internal/auth"
+	"restapi/internal/hasher"
+	"restapi/internal/models"
 	"restapi/internal/service"
+	"restapi/internal/storage"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/gorilla/mux"
 )
 
 var defaultTimeForCancel = 5
@@ -28,14 +36,38 @@ type AdRequest struct {
 	Description string  `json:"description"`
 	ImageURL    string  `json:"image_url"`
 	Price       float64 `json:"price"`
+	// CategoryID — категория из GET /categories; 0 или отсутствие — без категории
+	CategoryID int `json:"category_id,omitempty"`
+	// Images — галерея по порядку; если задана, image_url не нужен, обложкой станет первое изображение
+	Images []string `json:"images,omitempty"`
+	// Draft сохраняет объявление черновиком, не отправляя на модерацию (только при создании)
+	Draft bool `json:"draft,omitempty"`
+}
+
+// AdPatchRequest содержит только передаваемые поля объявления
+type AdPatchRequest struct {
+	Title       *string  `json:"title"`
+	Description *string  `json:"description"`
+	ImageURL    *string  `json:"image_url"`
+	Price       *float64 `json:"price"`
+	CategoryID  *int     `json:"category_id"`
+	// Images заменяет всю галерею, image_url — только обложку
+	Images *[]string `json:"images"`
 }
 
 type ErrorResponse struct {
 	Error string `json:"error"`
 }
 
+// RefreshRequest используется для обновления токенов и выхода
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
 type LoginResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
 }
 
 type AdResponse struct {
@@ -79,11 +111,16 @@ func (h *Handler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	defer cancel()
 	userID, err := h.svc.RegisterUser(ctx, req.Login, req.Password)
 	if err != nil {
-		if strings.Contains(err.Error(), "login already exists") {
+		if errors.Is(err, storage.ErrLoginExists) {
 			w.WriteHeader(http.StatusConflict)
 			json.NewEncoder(w).Encode(ErrorResponse{Error: "Login already exists"})
 			return
 		}
+		if errors.Is(err, hasher.ErrPasswordTooLong) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: fmt.Sprintf("Password must be at most %d bytes", hasher.BcryptMaxPasswordBytes)})
+			return
+		}
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to create user"})
 		return
@@ -108,20 +145,93 @@ func (h *Handler) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(defaultTimeForCancel)*time.Second)
 	defer cancel()
-	token, err := h.svc.LoginUser(ctx, req.Login, req.Password)
+	tokens, err := h.svc.LoginUser(ctx, req.Login, req.Password)
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid login or password"})
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid login or password"})
+			return
+		}
+		if errors.Is(err, service.ErrUserBanned) {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "User is banned"})
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to login"})
 		return
 	}
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(LoginResponse{Token: token})
+	json.NewEncoder(w).Encode(LoginResponse{Token: tokens.AccessToken, RefreshToken: tokens.RefreshToken, ExpiresIn: tokens.ExpiresIn})
+}
+
+// RefreshHandler обменивает refresh-токен на новую пару токенов
+func (h *Handler) RefreshHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+	if req.RefreshToken == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Refresh token is required"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(defaultTimeForCancel)*time.Second)
+	defer cancel()
+	tokens, err := h.svc.RefreshTokens(ctx, req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidToken) {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid refresh token"})
+			return
+		}
+		if errors.Is(err, service.ErrUserBanned) {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "User is banned"})
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to refresh token"})
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(LoginResponse{Token: tokens.AccessToken, RefreshToken: tokens.RefreshToken, ExpiresIn: tokens.ExpiresIn})
+}
+
+// LogoutHandler отзывает текущий access-токен и переданный refresh-токен
+func (h *Handler) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User not authenticated"})
+		return
+	}
+	var req RefreshRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+			return
+		}
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(defaultTimeForCancel)*time.Second)
+	defer cancel()
+	if err := h.svc.Logout(ctx, principal.UserID, principal.TokenID, principal.ExpiresAt, req.RefreshToken); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to logout"})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
 }
 
 // CreateAdHandler обрабатывает создание объявления
 func (h *Handler) CreateAdHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	userID, ok := r.Context().Value("user_id").(int)
+	principal, ok := auth.FromContext(r.Context())
 	if !ok {
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(ErrorResponse{Error: "User not authenticated"})
@@ -133,29 +243,19 @@ func (h *Handler) CreateAdHandler(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
 		return
 	}
-	if len(req.Title) < 3 || len(req.Title) > 100 {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Title must be between 3 and 100 characters"})
-		return
-	}
-	if len(req.Description) < 10 || len(req.Description) > 1000 {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Description must be between 10 and 1000 characters"})
-		return
-	}
-	if len(req.ImageURL) > 255 || !strings.HasPrefix(req.ImageURL, "http") {
+	if msg := validateAdRequest(req); msg != "" {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid image URL"})
+		json.NewEncoder(w).Encode(ErrorResponse{Error: msg})
 		return
 	}
-	if req.Price < 0 || req.Price > 1000000 {
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(defaultTimeForCancel)*time.Second)
+	defer cancel()
+	adID, err := h.svc.CreateAd(ctx, principal.UserID, req.Title, req.Description, req.CategoryID, req.gallery(), req.Price, req.Draft)
+	if errors.Is(err, service.ErrCategoryNotFound) {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Price must be between 0 and 1,000,000"})
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unknown category"})
 		return
 	}
-	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(defaultTimeForCancel)*time.Second)
-	defer cancel()
-	adID, err := h.svc.CreateAd(ctx, userID, req.Title, req.Description, req.ImageURL, req.Price)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to create ad"})
@@ -168,41 +268,373 @@ func (h *Handler) CreateAdHandler(w http.ResponseWriter, r *http.Request) {
 // GetAdsHandler обрабатывает получение списка объявлений
 func (h *Handler) GetAdsHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	page := 1
-	pageSize := 10
-	sortBy := r.URL.Query().Get("sort_by")
-	sortOrder := r.URL.Query().Get("sort_order")
-	minPrice := 0.0
-	maxPrice := 1000000.0
-	if p := r.URL.Query().Get("page"); p != "" {
+	filter, msg := adsFilterFromRequest(r)
+	if msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: msg})
+		return
+	}
+	h.writeAdsPage(w, r, filter)
+}
+
+// writeAdsPage отдает страницу ленты по фильтру: по курсору, если он передан, иначе по номеру страницы
+func (h *Handler) writeAdsPage(w http.ResponseWriter, r *http.Request, filter models.AdsFilter) {
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(defaultTimeForCancel)*time.Second)
+	defer cancel()
+	viewer := viewerFromRequest(r)
+	var page service.AdsPage
+	var err error
+	if r.URL.Query().Has("cursor") {
+		page, err = h.svc.GetAdsByCursor(ctx, filter, r.URL.Query().Get("cursor"), viewer)
+	} else {
+		page, err = h.svc.GetAdsPage(ctx, filter, viewer)
+	}
+	switch {
+	case errors.Is(err, service.ErrInvalidCursor):
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid cursor"})
+		return
+	case errors.Is(err, service.ErrCursorUnsupported):
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Cursor pagination is not supported for sort_by=relevance"})
+		return
+	case errors.Is(err, service.ErrCategoryNotFound):
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unknown category"})
+		return
+	case err != nil:
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to get ads"})
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newAdsPageResponse(r, page))
+}
+
+// adsFilterFromRequest читает параметры ленты из query string; некорректные числа заменяются значениями по умолчанию
+func adsFilterFromRequest(r *http.Request) (models.AdsFilter, string) {
+	query := r.URL.Query()
+	filter := models.AdsFilter{
+		Page:      1,
+		PageSize:  10,
+		SortBy:    query.Get("sort_by"),
+		SortOrder: query.Get("sort_order"),
+		MinPrice:  0.0,
+		MaxPrice:  1000000.0,
+		Query:     strings.TrimSpace(query.Get("q")),
+	}
+	if p := query.Get("page"); p != "" {
 		if pInt, err := strconv.Atoi(p); err == nil && pInt > 0 {
-			page = pInt
+			filter.Page = pInt
 		}
 	}
-	if ps := r.URL.Query().Get("page_size"); ps != "" {
+	if ps := query.Get("page_size"); ps != "" {
 		if psInt, err := strconv.Atoi(ps); err == nil && psInt > 0 {
-			pageSize = psInt
+			filter.PageSize = psInt
 		}
 	}
-	if mp := r.URL.Query().Get("min_price"); mp != "" {
+	if mp := query.Get("min_price"); mp != "" {
 		if mpFloat, err := strconv.ParseFloat(mp, 64); err == nil {
-			minPrice = mpFloat
+			filter.MinPrice = mpFloat
 		}
 	}
-	if mp := r.URL.Query().Get("max_price"); mp != "" {
+	if mp := query.Get("max_price"); mp != "" {
 		if mpFloat, err := strconv.ParseFloat(mp, 64); err == nil {
-			maxPrice = mpFloat
+			filter.MaxPrice = mpFloat
 		}
 	}
+	if c := query.Get("category"); c != "" {
+		cInt, err := strconv.Atoi(c)
+		if err != nil || cInt <= 0 {
+			return filter, "Invalid category"
+		}
+		filter.Category = cInt
+	}
+	if len(filter.Query) > 200 {
+		return filter, "Search query must be at most 200 characters"
+	}
+	return filter, ""
+}
+
+// GetAdHandler обрабатывает получение объявления по ID
+func (h *Handler) GetAdHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	adID, ok := adIDFromRequest(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid ad ID"})
+		return
+	}
 	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(defaultTimeForCancel)*time.Second)
 	defer cancel()
-	userID, _ := r.Context().Value("user_id").(int)
-	ads, err := h.svc.GetAds(ctx, page, pageSize, sortBy, sortOrder, minPrice, maxPrice, userID)
+	ad, err := h.svc.GetAd(ctx, adID, viewerFromRequest(r))
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to get ads"})
+		writeAdError(w, err, "Failed to get ad")
 		return
 	}
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(ads)
+	json.NewEncoder(w).Encode(ad)
+}
+
+// UpdateAdHandler обрабатывает полную замену объявления (PUT)
+func (h *Handler) UpdateAdHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User not authenticated"})
+		return
+	}
+	adID, ok := adIDFromRequest(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid ad ID"})
+		return
+	}
+	var req AdRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+	if msg := validateAdRequest(req); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: msg})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(defaultTimeForCancel)*time.Second)
+	defer cancel()
+	gallery := req.gallery()
+	ad, err := h.svc.UpdateAd(ctx, principal.UserID, adID, models.AdUpdate{
+		Title:       &req.Title,
+		Description: &req.Description,
+		Price:       &req.Price,
+		CategoryID:  &req.CategoryID,
+		Images:      &gallery,
+	})
+	if err != nil {
+		writeAdError(w, err, "Failed to update ad")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ad)
+}
+
+// PatchAdHandler обрабатывает частичное изменение объявления (PATCH)
+func (h *Handler) PatchAdHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User not authenticated"})
+		return
+	}
+	adID, ok := adIDFromRequest(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid ad ID"})
+		return
+	}
+	var req AdPatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+	if msg := validateAdPatchRequest(req); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: msg})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(defaultTimeForCancel)*time.Second)
+	defer cancel()
+	ad, err := h.svc.UpdateAd(ctx, principal.UserID, adID, models.AdUpdate{
+		Title:       req.Title,
+		Description: req.Description,
+		ImageURL:    req.ImageURL,
+		Price:       req.Price,
+		CategoryID:  req.CategoryID,
+		Images:      req.Images,
+	})
+	if err != nil {
+		writeAdError(w, err, "Failed to update ad")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ad)
+}
+
+// DeleteAdHandler обрабатывает удаление объявления
+func (h *Handler) DeleteAdHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User not authenticated"})
+		return
+	}
+	adID, ok := adIDFromRequest(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid ad ID"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(defaultTimeForCancel)*time.Second)
+	defer cancel()
+	if err := h.svc.DeleteAd(ctx, principal.UserID, adID); err != nil {
+		writeAdError(w, err, "Failed to delete ad")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// adIDFromRequest достает ID объявления из пути
+func adIDFromRequest(r *http.Request) (int, bool) {
+	adID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || adID <= 0 {
+		return 0, false
+	}
+	return adID, true
+}
+
+// viewerFromRequest описывает пользователя запроса для выборки объявлений; без токена — анонимный
+func viewerFromRequest(r *http.Request) models.Viewer {
+	principal, _ := auth.FromContext(r.Context())
+	return models.Viewer{
+		UserID:      principal.UserID,
+		CanModerate: principal.HasRole(auth.RoleModerator, auth.RoleAdmin),
+	}
+}
+
+// writeAdError отвечает 400/404/403/409 для известных ошибок и 500 для остальных
+func writeAdError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrCategoryNotFound):
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unknown category"})
+	case errors.Is(err, storage.ErrAdStatusConflict):
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Ad status does not allow this action"})
+	case errors.Is(err, storage.ErrAdNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Ad not found"})
+	case errors.Is(err, service.ErrForbidden):
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Only the author can modify this ad"})
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: fallback})
+	}
+}
+
+// validateAdRequest возвращает текст ошибки валидации или пустую строку
+func validateAdRequest(req AdRequest) string {
+	if msg := validateTitle(req.Title); msg != "" {
+		return msg
+	}
+	if msg := validateDescription(req.Description); msg != "" {
+		return msg
+	}
+	if len(req.Images) > 0 {
+		if msg := validateImages(req.Images); msg != "" {
+			return msg
+		}
+	} else if msg := validateImageURL(req.ImageURL); msg != "" {
+		return msg
+	}
+	if msg := validateCategoryID(req.CategoryID); msg != "" {
+		return msg
+	}
+	return validatePrice(req.Price)
+}
+
+// gallery возвращает галерею запроса; старые клиенты передают одно изображение в image_url
+func (req AdRequest) gallery() []string {
+	if len(req.Images) > 0 {
+		return req.Images
+	}
+	return []string{req.ImageURL}
+}
+
+// validateAdPatchRequest проверяет только переданные поля
+func validateAdPatchRequest(req AdPatchRequest) string {
+	if req.Title == nil && req.Description == nil && req.ImageURL == nil && req.Price == nil && req.Images == nil && req.CategoryID == nil {
+		return "No fields to update"
+	}
+	if req.Title != nil {
+		if msg := validateTitle(*req.Title); msg != "" {
+			return msg
+		}
+	}
+	if req.Description != nil {
+		if msg := validateDescription(*req.Description); msg != "" {
+			return msg
+		}
+	}
+	if req.ImageURL != nil {
+		if msg := validateImageURL(*req.ImageURL); msg != "" {
+			return msg
+		}
+	}
+	if req.Images != nil {
+		if msg := validateImages(*req.Images); msg != "" {
+			return msg
+		}
+	}
+	if req.CategoryID != nil {
+		if msg := validateCategoryID(*req.CategoryID); msg != "" {
+			return msg
+		}
+	}
+	if req.Price != nil {
+		return validatePrice(*req.Price)
+	}
+	return ""
+}
+
+func validateTitle(title string) string {
+	if len(title) < 3 || len(title) > 100 {
+		return "Title must be between 3 and 100 characters"
+	}
+	return ""
+}
+
+func validateDescription(description string) string {
+	if len(description) < 10 || len(description) > 1000 {
+		return "Description must be between 10 and 1000 characters"
+	}
+	return ""
+}
+
+func validateImageURL(imageURL string) string {
+	if len(imageURL) > 255 || !strings.HasPrefix(imageURL, "http") {
+		return "Invalid image URL"
+	}
+	return ""
+}
+
+func validateImages(images []string) string {
+	if len(images) == 0 || len(images) > service.MaxAdImages {
+		return fmt.Sprintf("An ad must have between 1 and %d images", service.MaxAdImages)
+	}
+	for _, url := range images {
+		if msg := validateImageURL(url); msg != "" {
+			return msg
+		}
+	}
+	return ""
+}
+
+func validateCategoryID(categoryID int) string {
+	if categoryID < 0 {
+		return "Invalid category"
+	}
+	return ""
+}
+
+func validatePrice(price float64) string {
+	if price < 0 || price > 1000000 {
+		return "Price must be between 0 and 1,000,000"
+	}
+	return ""
 }
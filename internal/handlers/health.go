@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// readinessTimeout ограничивает время проверок /readyz, чтобы probe не зависал на недоступной БД
+var readinessTimeout = 2 * time.Second
+
+// HealthzHandler отвечает, что процесс жив (liveness probe)
+func (h *Handler) HealthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.svc.Liveness())
+}
+
+// ReadyzHandler проверяет, готов ли сервис принимать трафик (readiness probe)
+func (h *Handler) ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+	report, ready := h.svc.Readiness(ctx)
+	if !ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	json.NewEncoder(w).Encode(report)
+}
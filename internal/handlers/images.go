@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"restapi/internal/auth"
+	"restapi/internal/images"
+	"restapi/internal/service"
+	"strings"
+	"time"
+)
+
+// multipartOverhead — запас на заголовки и границы multipart поверх размера самого файла
+const multipartOverhead = 64 << 10
+
+// ImageResponse — адреса загруженного изображения для image_url объявления
+type ImageResponse struct {
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	ContentType  string `json:"content_type"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+}
+
+// UploadImageHandler принимает multipart/form-data с файлом в поле "image"
+func (h *Handler) UploadImageHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User not authenticated"})
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, h.svc.MaxImageSize+multipartOverhead)
+	part, err := imagePart(r)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Image is too large"})
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Expected multipart/form-data with an \"image\" file field"})
+		return
+	}
+	defer part.Close()
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(defaultTimeForCancel)*time.Second)
+	defer cancel()
+	img, err := h.svc.UploadImage(ctx, principal.UserID, part)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		switch {
+		case errors.Is(err, images.ErrTooLarge), errors.As(err, &maxErr):
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Image is too large"})
+		case errors.Is(err, images.ErrUnsupportedType):
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Only JPEG, PNG and WebP images are supported"})
+		case errors.Is(err, images.ErrInvalidImage):
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid image"})
+		case errors.Is(err, service.ErrUploadsDisabled):
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Image uploads are disabled"})
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to upload image"})
+		}
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(ImageResponse{
+		URL:          img.URL,
+		ThumbnailURL: img.ThumbnailURL,
+		ContentType:  img.ContentType,
+		Width:        img.Width,
+		Height:       img.Height,
+	})
+}
+
+// imagePart находит поле "image" в multipart-теле, не загружая остальные части в память
+func imagePart(r *http.Request) (io.ReadCloser, error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == "image" {
+			return part, nil
+		}
+		part.Close()
+	}
+}
+
+// MediaHandler раздает файлы локального хранилища без листинга каталогов
+func MediaHandler(dir string) http.Handler {
+	files := http.StripPrefix("/media/", http.FileServer(http.Dir(dir)))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		files.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"restapi/internal/auth"
+	"restapi/internal/models"
+	"restapi/internal/service"
+	"restapi/internal/storage"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+)
+
+// MessageRequest — тело запроса с текстом сообщения
+type MessageRequest struct {
+	Body string `json:"body"`
+}
+
+// ThreadsPageResponse — страница веток переписки
+type ThreadsPageResponse struct {
+	Items      []models.Thread `json:"items"`
+	Page       int             `json:"page"`
+	PageSize   int             `json:"page_size"`
+	Total      int             `json:"total"`
+	TotalPages int             `json:"total_pages"`
+}
+
+// MessagesPageResponse — страница сообщений ветки
+type MessagesPageResponse struct {
+	Items      []models.Message `json:"items"`
+	Page       int              `json:"page"`
+	PageSize   int              `json:"page_size"`
+	Total      int              `json:"total"`
+	TotalPages int              `json:"total_pages"`
+}
+
+// StartThreadHandler отправляет первое (или очередное) сообщение автору объявления
+func (h *Handler) StartThreadHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User not authenticated"})
+		return
+	}
+	adID, ok := adIDFromRequest(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid ad ID"})
+		return
+	}
+	body, ok := decodeMessage(w, r)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(defaultTimeForCancel)*time.Second)
+	defer cancel()
+	thread, err := h.svc.StartThread(ctx, principal.UserID, adID, body)
+	if err != nil {
+		writeThreadError(w, err, "Failed to start thread")
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(thread)
+}
+
+// ListThreadsHandler возвращает ветки пользователя с числом непрочитанных сообщений
+func (h *Handler) ListThreadsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User not authenticated"})
+		return
+	}
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(defaultTimeForCancel)*time.Second)
+	defer cancel()
+	threads, err := h.svc.ListThreads(ctx, principal.UserID, page, pageSize)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to list threads"})
+		return
+	}
+	items := threads.Items
+	if items == nil {
+		items = []models.Thread{}
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ThreadsPageResponse{
+		Items:      items,
+		Page:       threads.Page,
+		PageSize:   threads.PageSize,
+		Total:      threads.Total,
+		TotalPages: (threads.Total + threads.PageSize - 1) / threads.PageSize,
+	})
+}
+
+// ThreadMessagesHandler возвращает сообщения ветки от старых к новым
+func (h *Handler) ThreadMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User not authenticated"})
+		return
+	}
+	threadID, ok := threadIDFromRequest(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid thread ID"})
+		return
+	}
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(defaultTimeForCancel)*time.Second)
+	defer cancel()
+	messages, err := h.svc.ThreadMessages(ctx, principal.UserID, threadID, page, pageSize)
+	if err != nil {
+		writeThreadError(w, err, "Failed to get messages")
+		return
+	}
+	items := messages.Items
+	if items == nil {
+		items = []models.Message{}
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(MessagesPageResponse{
+		Items:      items,
+		Page:       messages.Page,
+		PageSize:   messages.PageSize,
+		Total:      messages.Total,
+		TotalPages: (messages.Total + messages.PageSize - 1) / messages.PageSize,
+	})
+}
+
+// SendMessageHandler отправляет сообщение в существующую ветку
+func (h *Handler) SendMessageHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User not authenticated"})
+		return
+	}
+	threadID, ok := threadIDFromRequest(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid thread ID"})
+		return
+	}
+	body, ok := decodeMessage(w, r)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(defaultTimeForCancel)*time.Second)
+	defer cancel()
+	msg, err := h.svc.SendMessage(ctx, principal.UserID, threadID, body)
+	if err != nil {
+		writeThreadError(w, err, "Failed to send message")
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(msg)
+}
+
+// MarkThreadReadHandler отмечает ветку прочитанной
+func (h *Handler) MarkThreadReadHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User not authenticated"})
+		return
+	}
+	threadID, ok := threadIDFromRequest(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid thread ID"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(defaultTimeForCancel)*time.Second)
+	defer cancel()
+	if err := h.svc.MarkThreadRead(ctx, principal.UserID, threadID); err != nil {
+		writeThreadError(w, err, "Failed to mark thread as read")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeMessage читает и проверяет текст сообщения; при ошибке ответ уже записан
+func decodeMessage(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var req MessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return "", false
+	}
+	body := strings.TrimSpace(req.Body)
+	if body == "" || utf8.RuneCountInString(body) > 2000 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Message must be between 1 and 2000 characters"})
+		return "", false
+	}
+	return body, true
+}
+
+// threadIDFromRequest достает ID ветки из пути
+func threadIDFromRequest(r *http.Request) (int, bool) {
+	threadID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || threadID <= 0 {
+		return 0, false
+	}
+	return threadID, true
+}
+
+// writeThreadError дополняет writeAdError ошибками переписки
+func writeThreadError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, storage.ErrThreadNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Thread not found"})
+	case errors.Is(err, service.ErrSelfMessage):
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "You cannot message yourself"})
+	default:
+		writeAdError(w, err, fallback)
+	}
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"restapi/internal/auth"
+	"restapi/internal/service"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// RejectRequest — тело запроса отклонения объявления
+type RejectRequest struct {
+	Reason string `json:"reason"`
+}
+
+// SubmitAdHandler отправляет черновик или отклоненное объявление автора на модерацию
+func (h *Handler) SubmitAdHandler(w http.ResponseWriter, r *http.Request) {
+	h.changeOwnAdStatus(w, r, h.svc.SubmitAd)
+}
+
+// ArchiveAdHandler снимает объявление автора с показа
+func (h *Handler) ArchiveAdHandler(w http.ResponseWriter, r *http.Request) {
+	h.changeOwnAdStatus(w, r, h.svc.ArchiveAd)
+}
+
+func (h *Handler) changeOwnAdStatus(w http.ResponseWriter, r *http.Request, change func(ctx context.Context, userID, adID int) error) {
+	w.Header().Set("Content-Type", "application/json")
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User not authenticated"})
+		return
+	}
+	adID, ok := adIDFromRequest(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid ad ID"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(defaultTimeForCancel)*time.Second)
+	defer cancel()
+	if err := change(ctx, principal.UserID, adID); err != nil {
+		writeAdError(w, err, "Failed to change ad status")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ModerationQueueHandler возвращает объявления, ожидающие проверки (старые первыми)
+func (h *Handler) ModerationQueueHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.URL.Query().Has("cursor") {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Cursor pagination is not supported for the moderation queue"})
+		return
+	}
+	filter, msg := adsFilterFromRequest(r)
+	if msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: msg})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(defaultTimeForCancel)*time.Second)
+	defer cancel()
+	page, err := h.svc.ModerationQueue(ctx, filter)
+	if errors.Is(err, service.ErrCategoryNotFound) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unknown category"})
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to get moderation queue"})
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newAdsPageResponse(r, page))
+}
+
+// ApproveAdHandler публикует объявление из очереди
+func (h *Handler) ApproveAdHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	adID, ok := adIDFromRequest(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid ad ID"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(defaultTimeForCancel)*time.Second)
+	defer cancel()
+	if err := h.svc.ApproveAd(ctx, auth.UserID(r.Context()), adID); err != nil {
+		writeAdError(w, err, "Failed to approve ad")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RejectAdHandler отклоняет объявление из очереди с причиной
+func (h *Handler) RejectAdHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	adID, ok := adIDFromRequest(r)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid ad ID"})
+		return
+	}
+	var req RejectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" || utf8.RuneCountInString(req.Reason) > 500 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Reason is required and must be at most 500 characters"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(defaultTimeForCancel)*time.Second)
+	defer cancel()
+	if err := h.svc.RejectAd(ctx, auth.UserID(r.Context()), adID, req.Reason); err != nil {
+		writeAdError(w, err, "Failed to reject ad")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
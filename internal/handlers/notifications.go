@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"restapi/internal/auth"
+	"restapi/internal/models"
+	"restapi/internal/storage"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// NotificationsPageResponse — страница входящих уведомлений
+type NotificationsPageResponse struct {
+	Items       []models.Notification `json:"items"`
+	Page        int                   `json:"page"`
+	PageSize    int                   `json:"page_size"`
+	Total       int                   `json:"total"`
+	TotalPages  int                   `json:"total_pages"`
+	UnreadCount int                   `json:"unread_count"`
+}
+
+// NotificationsHandler возвращает входящие уведомления пользователя; ?unread=true оставляет только непрочитанные
+func (h *Handler) NotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User not authenticated"})
+		return
+	}
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+	unreadOnly, _ := strconv.ParseBool(r.URL.Query().Get("unread"))
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(defaultTimeForCancel)*time.Second)
+	defer cancel()
+	notifications, err := h.svc.Notifications(ctx, principal.UserID, unreadOnly, page, pageSize)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to list notifications"})
+		return
+	}
+	items := notifications.Items
+	if items == nil {
+		items = []models.Notification{}
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(NotificationsPageResponse{
+		Items:       items,
+		Page:        notifications.Page,
+		PageSize:    notifications.PageSize,
+		Total:       notifications.Total,
+		TotalPages:  (notifications.Total + notifications.PageSize - 1) / notifications.PageSize,
+		UnreadCount: notifications.Unread,
+	})
+}
+
+// MarkNotificationReadHandler отмечает уведомление прочитанным
+func (h *Handler) MarkNotificationReadHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User not authenticated"})
+		return
+	}
+	notificationID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || notificationID <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid notification ID"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(defaultTimeForCancel)*time.Second)
+	defer cancel()
+	err = h.svc.MarkNotificationRead(ctx, principal.UserID, notificationID)
+	switch {
+	case errors.Is(err, storage.ErrNotificationNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Notification not found"})
+		return
+	case err != nil:
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to mark notification as read"})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MarkAllNotificationsReadHandler отмечает прочитанными все уведомления пользователя
+func (h *Handler) MarkAllNotificationsReadHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User not authenticated"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(defaultTimeForCancel)*time.Second)
+	defer cancel()
+	if err := h.svc.MarkAllNotificationsRead(ctx, principal.UserID); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to mark notifications as read"})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"net/http"
+	"restapi/internal/models"
+	"restapi/internal/service"
+	"strconv"
+)
+
+// AdsPageResponse — ответ ленты объявлений.
+// При пагинации курсором page не заполняется, а переход идет по next_cursor/prev_cursor
+type AdsPageResponse struct {
+	Items      []models.Ad `json:"items"`
+	Page       int         `json:"page,omitempty"`
+	PageSize   int         `json:"page_size"`
+	Total      int         `json:"total"`
+	TotalPages int         `json:"total_pages"`
+	Links      PageLinks   `json:"links"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	PrevCursor string      `json:"prev_cursor,omitempty"`
+}
+
+// PageLinks — ссылки на текущую и соседние страницы с теми же фильтрами
+type PageLinks struct {
+	Self  string `json:"self"`
+	Next  string `json:"next,omitempty"`
+	Prev  string `json:"prev,omitempty"`
+	First string `json:"first,omitempty"`
+	Last  string `json:"last,omitempty"`
+}
+
+// newAdsPageResponse собирает ответ ленты; ссылки строятся из URL запроса, чтобы сохранить фильтры
+func newAdsPageResponse(r *http.Request, page service.AdsPage) AdsPageResponse {
+	items := page.Items
+	if items == nil {
+		items = []models.Ad{}
+	}
+	resp := AdsPageResponse{
+		Items:      items,
+		PageSize:   page.PageSize,
+		Total:      page.Total,
+		TotalPages: page.TotalPages(),
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
+	}
+
+	if r.URL.Query().Has("cursor") {
+		resp.Links = PageLinks{
+			Self:  pageLink(r, page.PageSize, "cursor", r.URL.Query().Get("cursor")),
+			First: pageLink(r, page.PageSize, "cursor", ""),
+		}
+		if page.NextCursor != "" {
+			resp.Links.Next = pageLink(r, page.PageSize, "cursor", page.NextCursor)
+		}
+		if page.PrevCursor != "" {
+			resp.Links.Prev = pageLink(r, page.PageSize, "cursor", page.PrevCursor)
+		}
+		return resp
+	}
+
+	resp.Page = page.Page
+	last := max(resp.TotalPages, 1)
+	resp.Links = PageLinks{
+		Self:  pageLink(r, page.PageSize, "page", strconv.Itoa(page.Page)),
+		First: pageLink(r, page.PageSize, "page", "1"),
+		Last:  pageLink(r, page.PageSize, "page", strconv.Itoa(last)),
+	}
+	if page.Page < resp.TotalPages {
+		resp.Links.Next = pageLink(r, page.PageSize, "page", strconv.Itoa(page.Page+1))
+	}
+	if page.Page > 1 {
+		resp.Links.Prev = pageLink(r, page.PageSize, "page", strconv.Itoa(min(page.Page-1, last)))
+	}
+	return resp
+}
+
+// pageLink возвращает путь запроса с замененным параметром пагинации (page или cursor)
+func pageLink(r *http.Request, pageSize int, key, value string) string {
+	query := r.URL.Query()
+	query.Del("page")
+	query.Del("cursor")
+	query.Set("page_size", strconv.Itoa(pageSize))
+	query.Set(key, value)
+	return r.URL.Path + "?" + query.Encode()
+}
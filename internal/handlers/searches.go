@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"restapi/internal/auth"
+	"restapi/internal/models"
+	"restapi/internal/service"
+	"restapi/internal/storage"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+)
+
+// SavedSearchRequest — тело запроса сохранения поиска; поля повторяют параметры GET /ads
+type SavedSearchRequest struct {
+	Name      string   `json:"name"`
+	Query     string   `json:"q"`
+	MinPrice  *float64 `json:"min_price"`
+	MaxPrice  *float64 `json:"max_price"`
+	Category  int      `json:"category"`
+	SortBy    string   `json:"sort_by"`
+	SortOrder string   `json:"sort_order"`
+}
+
+// CreateSavedSearchHandler сохраняет поиск, по которому пользователь будет получать уведомления о новых объявлениях
+func (h *Handler) CreateSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User not authenticated"})
+		return
+	}
+	var req SavedSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+	search, msg := req.savedSearch(principal.UserID)
+	if msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: msg})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(defaultTimeForCancel)*time.Second)
+	defer cancel()
+	search, err := h.svc.CreateSavedSearch(ctx, search)
+	if err != nil {
+		writeSavedSearchError(w, err, "Failed to save search")
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(search)
+}
+
+// ListSavedSearchesHandler возвращает сохраненные поиски пользователя
+func (h *Handler) ListSavedSearchesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User not authenticated"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(defaultTimeForCancel)*time.Second)
+	defer cancel()
+	searches, err := h.svc.SavedSearches(ctx, principal.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to list saved searches"})
+		return
+	}
+	if searches == nil {
+		searches = []models.SavedSearch{}
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(searches)
+}
+
+// DeleteSavedSearchHandler удаляет сохраненный поиск пользователя
+func (h *Handler) DeleteSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "User not authenticated"})
+		return
+	}
+	searchID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || searchID <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid saved search ID"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(defaultTimeForCancel)*time.Second)
+	defer cancel()
+	if err := h.svc.DeleteSavedSearch(ctx, principal.UserID, searchID); err != nil {
+		writeSavedSearchError(w, err, "Failed to delete saved search")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// savedSearch проверяет запрос и собирает из него поиск; цены по умолчанию те же, что у GET /ads
+func (req SavedSearchRequest) savedSearch(userID int) (models.SavedSearch, string) {
+	search := models.SavedSearch{
+		UserID:    userID,
+		Name:      strings.TrimSpace(req.Name),
+		Query:     strings.TrimSpace(req.Query),
+		MinPrice:  0.0,
+		MaxPrice:  1000000.0,
+		Category:  req.Category,
+		SortBy:    req.SortBy,
+		SortOrder: req.SortOrder,
+	}
+	if req.MinPrice != nil {
+		search.MinPrice = *req.MinPrice
+	}
+	if req.MaxPrice != nil {
+		search.MaxPrice = *req.MaxPrice
+	}
+	switch {
+	case search.Name == "" || utf8.RuneCountInString(search.Name) > 100:
+		return search, "Name must be between 1 and 100 characters"
+	case utf8.RuneCountInString(search.Query) > 200:
+		return search, "Search query must be at most 200 characters"
+	case search.MinPrice < 0 || search.MaxPrice < search.MinPrice:
+		return search, "Invalid price range"
+	case search.Category < 0:
+		return search, "Invalid category"
+	}
+	return search, ""
+}
+
+// writeSavedSearchError отвечает 400/404 для известных ошибок сохраненных поисков и 500 для остальных
+func writeSavedSearchError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, storage.ErrSavedSearchNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Saved search not found"})
+	case errors.Is(err, service.ErrTooManySavedSearches):
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Saved search limit reached"})
+	case errors.Is(err, service.ErrCategoryNotFound):
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Unknown category"})
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: fallback})
+	}
+}
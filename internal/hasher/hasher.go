@@ -0,0 +1,179 @@
+package hasher
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	Argon2id = "argon2id"
+	Bcrypt   = "bcrypt"
+)
+
+// BcryptMaxPasswordBytes — предел bcrypt: более длинные пароли он молча обрезает при проверке
+const BcryptMaxPasswordBytes = 72
+
+var (
+	// ErrUnknownFormat возвращается, если сохраненное значение не похоже ни на один поддерживаемый хеш
+	ErrUnknownFormat = errors.New("unknown password hash format")
+	// ErrPasswordTooLong возвращается, если пароль не помещается в bcrypt
+	ErrPasswordTooLong = fmt.Errorf("password exceeds %d bytes", BcryptMaxPasswordBytes)
+)
+
+// Hasher хеширует и проверяет пароли
+type Hasher interface {
+	Hash(password string) (string, error)
+	Verify(encoded, password string) (bool, error)
+	NeedsRehash(encoded string) bool
+}
+
+type argon2Params struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+	saltLen uint32
+	keyLen  uint32
+}
+
+var defaultArgon2Params = argon2Params{
+	memory:  64 * 1024,
+	time:    1,
+	threads: 4,
+	saltLen: 16,
+	keyLen:  32,
+}
+
+// passwordHasher хеширует выбранным алгоритмом, но проверяет хеши любого поддерживаемого формата,
+// чтобы смена алгоритма в конфиге не ломала вход существующих пользователей
+type passwordHasher struct {
+	algorithm  string
+	argon2     argon2Params
+	bcryptCost int
+}
+
+// New создает Hasher для указанного алгоритма (по умолчанию argon2id)
+func New(algorithm string) (Hasher, error) {
+	switch algorithm {
+	case "", Argon2id:
+		algorithm = Argon2id
+	case Bcrypt:
+	default:
+		return nil, fmt.Errorf("unsupported password hasher: %s", algorithm)
+	}
+	return &passwordHasher{
+		algorithm:  algorithm,
+		argon2:     defaultArgon2Params,
+		bcryptCost: bcrypt.DefaultCost,
+	}, nil
+}
+
+// Hash возвращает закодированный хеш пароля
+func (h *passwordHasher) Hash(password string) (string, error) {
+	if h.algorithm == Bcrypt {
+		if len(password) > BcryptMaxPasswordBytes {
+			return "", ErrPasswordTooLong
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost)
+		if err != nil {
+			return "", fmt.Errorf("failed to hash password: %w", err)
+		}
+		return string(hash), nil
+	}
+
+	salt := make([]byte, h.argon2.saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+	p := h.argon2
+	key := argon2.IDKey([]byte(password), salt, p.time, p.memory, p.threads, p.keyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.memory, p.time, p.threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify сравнивает пароль с хешем за постоянное время
+func (h *passwordHasher) Verify(encoded, password string) (bool, error) {
+	switch {
+	case isArgon2id(encoded):
+		p, salt, key, err := decodeArgon2id(encoded)
+		if err != nil {
+			return false, err
+		}
+		other := argon2.IDKey([]byte(password), salt, p.time, p.memory, p.threads, p.keyLen)
+		return subtle.ConstantTimeCompare(key, other) == 1, nil
+	case isBcrypt(encoded):
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to verify password: %w", err)
+		}
+		return true, nil
+	default:
+		return false, ErrUnknownFormat
+	}
+}
+
+// NeedsRehash сообщает, что хеш сделан другим алгоритмом или с устаревшими параметрами
+func (h *passwordHasher) NeedsRehash(encoded string) bool {
+	switch {
+	case isArgon2id(encoded):
+		if h.algorithm != Argon2id {
+			return true
+		}
+		p, _, _, err := decodeArgon2id(encoded)
+		return err != nil || p != h.argon2
+	case isBcrypt(encoded):
+		if h.algorithm != Bcrypt {
+			return true
+		}
+		cost, err := bcrypt.Cost([]byte(encoded))
+		return err != nil || cost != h.bcryptCost
+	default:
+		return true
+	}
+}
+
+func isArgon2id(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$")
+}
+
+func isBcrypt(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+func decodeArgon2id(encoded string) (argon2Params, []byte, []byte, error) {
+	var p argon2Params
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return p, nil, nil, ErrUnknownFormat
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, fmt.Errorf("unsupported argon2 version: %s", parts[2])
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil {
+		return p, nil, nil, fmt.Errorf("invalid argon2 params: %w", err)
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, fmt.Errorf("invalid argon2 salt: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return p, nil, nil, fmt.Errorf("invalid argon2 key: %w", err)
+	}
+	p.saltLen = uint32(len(salt))
+	p.keyLen = uint32(len(key))
+	return p, salt, key, nil
+}
@@ -0,0 +1,121 @@
+package hasher
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHashAndVerify(t *testing.T) {
+	for _, algorithm := range []string{Argon2id, Bcrypt} {
+		t.Run(algorithm, func(t *testing.T) {
+			h, err := New(algorithm)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			encoded, err := h.Hash("secret1")
+			if err != nil {
+				t.Fatalf("Hash: %v", err)
+			}
+			if encoded == "secret1" {
+				t.Fatal("Hash returned the password itself")
+			}
+			other, err := h.Hash("secret1")
+			if err != nil {
+				t.Fatalf("Hash: %v", err)
+			}
+			if other == encoded {
+				t.Fatal("two hashes of the same password are equal, salt is not random")
+			}
+
+			tests := []struct {
+				password string
+				want     bool
+			}{
+				{"secret1", true},
+				{"secret2", false},
+				{"", false},
+			}
+			for _, tt := range tests {
+				ok, err := h.Verify(encoded, tt.password)
+				if err != nil {
+					t.Fatalf("Verify(%q): %v", tt.password, err)
+				}
+				if ok != tt.want {
+					t.Fatalf("Verify(%q) = %v, want %v", tt.password, ok, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyOtherAlgorithm(t *testing.T) {
+	argon, _ := New(Argon2id)
+	bcryptHasher, _ := New(Bcrypt)
+	encoded, err := argon.Hash("secret1")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	// после смены алгоритма в конфиге старые хеши должны по-прежнему проверяться
+	ok, err := bcryptHasher.Verify(encoded, "secret1")
+	if err != nil || !ok {
+		t.Fatalf("Verify = %v, %v, want true", ok, err)
+	}
+	if _, err := argon.Verify("secret1", "secret1"); !errors.Is(err, ErrUnknownFormat) {
+		t.Fatalf("Verify of plain text = %v, want ErrUnknownFormat", err)
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	argon, _ := New(Argon2id)
+	bcryptHasher, _ := New(Bcrypt)
+	argonHash, _ := argon.Hash("secret1")
+	bcryptHash, _ := bcryptHasher.Hash("secret1")
+	cheapBcrypt, _ := bcrypt.GenerateFromPassword([]byte("secret1"), bcrypt.MinCost)
+	weakArgon := strings.Replace(argonHash, "m=65536,t=1", "m=32768,t=1", 1)
+
+	tests := []struct {
+		name    string
+		hasher  Hasher
+		encoded string
+		want    bool
+	}{
+		{"argon2id is current", argon, argonHash, false},
+		{"bcrypt is current", bcryptHasher, bcryptHash, false},
+		{"argon2id to bcrypt", bcryptHasher, argonHash, true},
+		{"bcrypt to argon2id", argon, bcryptHash, true},
+		{"bcrypt cost changed", bcryptHasher, string(cheapBcrypt), true},
+		{"argon2id params changed", argon, weakArgon, true},
+		{"plain text", argon, "secret1", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hasher.NeedsRehash(tt.encoded); got != tt.want {
+				t.Fatalf("NeedsRehash = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBcryptPasswordTooLong(t *testing.T) {
+	h, _ := New(Bcrypt)
+	if _, err := h.Hash(strings.Repeat("a", BcryptMaxPasswordBytes)); err != nil {
+		t.Fatalf("Hash of %d bytes: %v", BcryptMaxPasswordBytes, err)
+	}
+	if _, err := h.Hash(strings.Repeat("a", BcryptMaxPasswordBytes+1)); !errors.Is(err, ErrPasswordTooLong) {
+		t.Fatalf("Hash of %d bytes = %v, want ErrPasswordTooLong", BcryptMaxPasswordBytes+1, err)
+	}
+	// argon2id длину не ограничивает
+	argon, _ := New(Argon2id)
+	if _, err := argon.Hash(strings.Repeat("a", 255)); err != nil {
+		t.Fatalf("argon2id Hash of 255 bytes: %v", err)
+	}
+}
+
+func TestNewUnknownAlgorithm(t *testing.T) {
+	if _, err := New("md5"); err == nil {
+		t.Fatal("New(md5) succeeded, want error")
+	}
+}
@@ -0,0 +1,123 @@
+package images
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// ThumbnailSize — максимальная сторона миниатюры в пикселях
+	ThumbnailSize = 320
+	// maxPixels защищает от "бомб": маленький файл с огромными размерами распаковывается в гигабайты
+	maxPixels    = 40_000_000
+	jpegQuality  = 85
+	sniffLength  = 512
+	contentPNG   = "image/png"
+	contentJPEG  = "image/jpeg"
+	contentWebP  = "image/webp"
+	extensionPNG = ".png"
+	extensionJPG = ".jpg"
+)
+
+var (
+	// ErrTooLarge возвращается, если файл больше лимита или у изображения слишком большие размеры
+	ErrTooLarge = errors.New("image is too large")
+	// ErrUnsupportedType возвращается для файлов, не являющихся JPEG, PNG или WebP
+	ErrUnsupportedType = errors.New("unsupported image type")
+	// ErrInvalidImage возвращается, если файл не удалось декодировать
+	ErrInvalidImage = errors.New("invalid image")
+)
+
+// Processed — перекодированное изображение и его миниатюра.
+// Перекодирование убирает метаданные (EXIF с геопозицией) и гарантирует, что файл действительно картинка
+type Processed struct {
+	Original    []byte
+	Thumbnail   []byte
+	ContentType string
+	// Ext — расширение файла для ContentType (".jpg" или ".png")
+	Ext    string
+	Width  int
+	Height int
+}
+
+// Process читает не больше maxSize байт, проверяет тип по содержимому и строит миниатюру.
+// PNG остается PNG (ради прозрачности), JPEG и WebP сохраняются как JPEG
+func Process(r io.Reader, maxSize int64) (Processed, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return Processed{}, fmt.Errorf("failed to read image: %w", err)
+	}
+	if int64(len(data)) > maxSize {
+		return Processed{}, ErrTooLarge
+	}
+	detected := http.DetectContentType(data[:min(len(data), sniffLength)])
+	if detected != contentJPEG && detected != contentPNG && detected != contentWebP {
+		return Processed{}, ErrUnsupportedType
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Processed{}, ErrInvalidImage
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return Processed{}, ErrTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Processed{}, ErrInvalidImage
+	}
+
+	p := Processed{ContentType: contentJPEG, Ext: extensionJPG, Width: cfg.Width, Height: cfg.Height}
+	if detected == contentPNG {
+		p.ContentType, p.Ext = contentPNG, extensionPNG
+	}
+	if p.Original, err = encode(img, p.ContentType); err != nil {
+		return Processed{}, err
+	}
+	if p.Thumbnail, err = encode(thumbnail(img), p.ContentType); err != nil {
+		return Processed{}, err
+	}
+	return p, nil
+}
+
+// thumbnail уменьшает изображение с сохранением пропорций; маленькие картинки не увеличиваются
+func thumbnail(img image.Image) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= ThumbnailSize && h <= ThumbnailSize {
+		return img
+	}
+	if w >= h {
+		h = max(1, h*ThumbnailSize/w)
+		w = ThumbnailSize
+	} else {
+		w = max(1, w*ThumbnailSize/h)
+		h = ThumbnailSize
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Over, nil)
+	return dst
+}
+
+func encode(img image.Image, contentType string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if contentType == contentPNG {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"context"
 	"fmt"
 
 	"go.uber.org/zap"
@@ -24,3 +25,18 @@ func NewLogger(level string) (*zap.SugaredLogger, error) {
 
 	return logger.Sugar(), nil
 }
+
+type ctxKey struct{}
+
+// WithContext сохраняет логгер запроса в контексте
+func WithContext(ctx context.Context, l *zap.SugaredLogger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext возвращает логгер запроса или fallback, если его нет в контексте
+func FromContext(ctx context.Context, fallback *zap.SugaredLogger) *zap.SugaredLogger {
+	if l, ok := ctx.Value(ctxKey{}).(*zap.SugaredLogger); ok && l != nil {
+		return l
+	}
+	return fallback
+}
@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "restapi"
+
+var (
+	// HTTPRequestsTotal считает запросы по шаблону маршрута gorilla/mux, а не по сырому пути
+	HTTPRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Total number of HTTP requests by route template, method and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route template and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	UserRegistrationsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "user_registrations_total",
+		Help:      "Total number of successfully registered users.",
+	})
+
+	// LoginsTotal размечен result=success|failure
+	LoginsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Total number of login attempts by result.",
+	}, []string{"result"})
+
+	AdsCreatedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ads_created_total",
+		Help:      "Total number of created ads.",
+	})
+
+	ImagesUploadedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "images_uploaded_total",
+		Help:      "Total number of uploaded images.",
+	})
+
+	MessagesSentTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_sent_total",
+		Help:      "Total number of messages sent between buyers and sellers.",
+	})
+
+	// NotificationsCreatedTotal размечен типом уведомления
+	NotificationsCreatedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_created_total",
+		Help:      "Total number of in-app notifications created by type.",
+	}, []string{"type"})
+
+	// EventStreams — число открытых SSE-подключений
+	EventStreams = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "event_streams",
+		Help:      "Number of open server-sent event streams.",
+	})
+
+	// AdsModeratedTotal размечен decision=approved|rejected
+	AdsModeratedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ads_moderated_total",
+		Help:      "Total number of moderation decisions by decision.",
+	}, []string{"decision"})
+)
+
+// RegisterDBStats публикует sql.DB.Stats() пула соединений как gauges go_sql_*
+func RegisterDBStats(db *sql.DB, dbName string) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, dbName))
+}
+
+// Handler отдает метрики в формате Prometheus
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
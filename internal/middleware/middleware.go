@@ -3,50 +3,160 @@ package middleware
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"restapi/internal/auth"
+	"restapi/internal/logger"
+	"restapi/internal/storage"
+	"restapi/internal/user"
 
 	"github.com/golang-jwt/jwt/v5"
 	"go.uber.org/zap"
 )
 
-// AuthMiddleware проверяет JWT-токен и добавляет userID в контекст
-func AuthMiddleware(logger *zap.SugaredLogger, jwtSecret string) func(http.Handler) http.Handler {
+// RevocationChecker сообщает, отозван ли access-токен с данным jti
+type RevocationChecker interface {
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+// UserLoader возвращает текущее состояние пользователя: роль и блокировку
+type UserLoader interface {
+	GetUserByID(ctx context.Context, userID int) (user.User, error)
+}
+
+// PrincipalStore нужен для аутентификации запроса: проверки отзыва токена и текущего состояния пользователя
+type PrincipalStore interface {
+	RevocationChecker
+	UserLoader
+}
+
+// AuthMiddleware проверяет JWT-токен и добавляет auth.Principal в контекст
+func AuthMiddleware(log *zap.SugaredLogger, jwtSecret string, store PrincipalStore) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Content-Type", "application/json")
-			tokenString := r.Header.Get("Authorization")
-			if tokenString == "" {
+			if r.Header.Get("Authorization") == "" {
 				w.WriteHeader(http.StatusUnauthorized)
 				json.NewEncoder(w).Encode(map[string]string{"error": "Authorization header missing"})
 				return
 			}
-			token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					logger.Errorf("Unexpected signing method: %v", token.Header["alg"])
-					return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
-				}
-				return []byte(jwtSecret), nil
-			})
-			if err != nil || !token.Valid {
-				w.WriteHeader(http.StatusUnauthorized)
-				json.NewEncoder(w).Encode(map[string]string{"error": "Invalid token"})
+			ctx, status, msg := authenticate(r, log, jwtSecret, store)
+			if status != 0 {
+				w.WriteHeader(status)
+				json.NewEncoder(w).Encode(map[string]string{"error": msg})
 				return
 			}
-			claims, ok := token.Claims.(jwt.MapClaims)
-			if !ok {
-				w.WriteHeader(http.StatusUnauthorized)
-				json.NewEncoder(w).Encode(map[string]string{"error": "Invalid token claims"})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// OptionalAuthMiddleware добавляет пользователя в контекст, если передан валидный токен.
+// Запрос без заголовка Authorization проходит анонимно, а некорректный, просроченный или отозванный токен отклоняется
+func OptionalAuthMiddleware(log *zap.SugaredLogger, jwtSecret string, store PrincipalStore) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				next.ServeHTTP(w, r)
 				return
 			}
-			userID, ok := claims["user_id"].(float64)
+			ctx, status, msg := authenticate(r, log, jwtSecret, store)
+			if status != 0 {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(status)
+				json.NewEncoder(w).Encode(map[string]string{"error": msg})
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// authenticate разбирает токен из заголовка Authorization и возвращает контекст с пользователем.
+// Роль и блокировка берутся из хранилища, а не из токена, чтобы понижение или бан действовали сразу.
+// При ошибке возвращается HTTP-статус и сообщение для клиента
+func authenticate(r *http.Request, log *zap.SugaredLogger, jwtSecret string, store PrincipalStore) (context.Context, int, string) {
+	token, err := jwt.Parse(r.Header.Get("Authorization"), func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			logger.FromContext(r.Context(), log).Errorf("Unexpected signing method: %v", token.Header["alg"])
+			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(jwtSecret), nil
+	})
+	if err != nil || !token.Valid {
+		return nil, http.StatusUnauthorized, "Invalid token"
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, http.StatusUnauthorized, "Invalid token claims"
+	}
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return nil, http.StatusUnauthorized, "Invalid user ID in token"
+	}
+	jti, ok := claims["jti"].(string)
+	if !ok || jti == "" {
+		return nil, http.StatusUnauthorized, "Invalid token claims"
+	}
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return nil, http.StatusUnauthorized, "Invalid token claims"
+	}
+	isRevoked, err := store.IsAccessTokenRevoked(r.Context(), jti)
+	if err != nil {
+		logger.FromContext(r.Context(), log).Errorf("Failed to check token revocation: %v", err)
+		return nil, http.StatusInternalServerError, "Failed to verify token"
+	}
+	if isRevoked {
+		return nil, http.StatusUnauthorized, "Token has been revoked"
+	}
+	u, err := store.GetUserByID(r.Context(), int(userID))
+	if errors.Is(err, storage.ErrUserNotFound) {
+		return nil, http.StatusUnauthorized, "User not found"
+	}
+	if err != nil {
+		logger.FromContext(r.Context(), log).Errorf("Failed to load user: %v", err)
+		return nil, http.StatusInternalServerError, "Failed to verify token"
+	}
+	if u.Banned() {
+		return nil, http.StatusForbidden, "User is banned"
+	}
+	roles := []string{auth.RoleUser}
+	if u.Role != "" {
+		roles = []string{u.Role}
+	}
+	ctx := withRequestUser(r.Context(), log, int(userID))
+	ctx = auth.WithPrincipal(ctx, auth.Principal{
+		UserID:    int(userID),
+		Login:     u.Login,
+		Roles:     roles,
+		TokenID:   jti,
+		ExpiresAt: exp.Time,
+		Method:    auth.MethodJWT,
+	})
+	return ctx, 0, ""
+}
+
+// RequireRole пропускает только пользователей хотя бы с одной из ролей; ставится после AuthMiddleware,
+// которая берет текущую роль из хранилища
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := auth.FromContext(r.Context())
 			if !ok {
+				w.Header().Set("Content-Type", "application/json")
 				w.WriteHeader(http.StatusUnauthorized)
-				json.NewEncoder(w).Encode(map[string]string{"error": "Invalid user ID in token"})
+				json.NewEncoder(w).Encode(map[string]string{"error": "User not authenticated"})
 				return
 			}
-			ctx := context.WithValue(r.Context(), "user_id", int(userID))
-			next.ServeHTTP(w, r.WithContext(ctx))
+			if !principal.HasRole(roles...) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusForbidden)
+				json.NewEncoder(w).Encode(map[string]string{"error": "Insufficient permissions"})
+				return
+			}
+			next.ServeHTTP(w, r)
 		})
 	}
 }
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"restapi/internal/logger"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDPattern ограничивает входящий X-Request-ID, чтобы в логи не попадал произвольный мусор
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+type requestIDKey struct{}
+
+// accessEntry заполняется по ходу обработки запроса (например, AuthMiddleware добавляет userID)
+// и читается при записи итоговой строки access log
+type accessEntry struct {
+	userID int
+}
+
+type accessEntryKey struct{}
+
+// routeKey хранит шаблон маршрута, найденный RequestLogger, чтобы MetricsMiddleware не искал его повторно
+type routeKey struct{}
+
+// RequestID возвращает ID текущего запроса
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// RequestLogger назначает или пробрасывает X-Request-ID, кладет в контекст логгер запроса
+// и пишет одну строку access log на запрос. Оборачивает router целиком, чтобы в лог попадали и 404/405
+func RequestLogger(base *zap.SugaredLogger, router *mux.Router) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			requestID := r.Header.Get(RequestIDHeader)
+			if !requestIDPattern.MatchString(requestID) {
+				requestID = newRequestID()
+			}
+			w.Header().Set(RequestIDHeader, requestID)
+
+			route := routeTemplate(router, r)
+			reqLogger := base.With("request_id", requestID, "method", r.Method, "route", route)
+			entry := &accessEntry{}
+			ctx := context.WithValue(r.Context(), requestIDKey{}, requestID)
+			ctx = context.WithValue(ctx, accessEntryKey{}, entry)
+			ctx = context.WithValue(ctx, routeKey{}, route)
+			ctx = logger.WithContext(ctx, reqLogger)
+
+			rec := &statusRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r.WithContext(ctx))
+
+			fields := []interface{}{
+				"path", r.URL.Path,
+				"status", rec.statusCode(),
+				"bytes", rec.bytes,
+				"duration_ms", float64(time.Since(start).Microseconds()) / 1000,
+				"remote_addr", r.RemoteAddr,
+			}
+			if entry.userID != 0 {
+				fields = append(fields, "user_id", entry.userID)
+			}
+			reqLogger.Infow("request completed", fields...)
+		})
+	}
+}
+
+// withRequestUser добавляет userID в access log и в логгер запроса
+func withRequestUser(ctx context.Context, fallback *zap.SugaredLogger, userID int) context.Context {
+	if entry, ok := ctx.Value(accessEntryKey{}).(*accessEntry); ok {
+		entry.userID = userID
+	}
+	return logger.WithContext(ctx, logger.FromContext(ctx, fallback).With("user_id", userID))
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
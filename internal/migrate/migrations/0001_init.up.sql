@@ -14,4 +14,4 @@ CREATE TABLE IF NOT EXISTS ads (
     price DECIMAL(10, 2)  NOT NULL CHECK (price >= 0),
     user_id INTEGER REFERENCES users(id),
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-);
\ No newline at end of file
+);
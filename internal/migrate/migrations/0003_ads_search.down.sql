@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS ads_search_vector_idx;
+ALTER TABLE ads DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,9 @@
+-- Объявления бывают на русском и английском, поэтому индексируем обе конфигурации
+ALTER TABLE ads ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('russian', coalesce(description, '')), 'B') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'B')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS ads_search_vector_idx ON ads USING GIN (search_vector);
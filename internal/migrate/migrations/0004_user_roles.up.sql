@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'user'
+    CHECK (role IN ('user', 'moderator', 'admin'));
+-- banned_at заполнен у заблокированных пользователей: им запрещен вход и обновление токенов
+ALTER TABLE users ADD COLUMN IF NOT EXISTS banned_at TIMESTAMP;
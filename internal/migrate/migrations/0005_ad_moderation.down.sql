@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS ads_status_created_at_idx;
+ALTER TABLE ads DROP COLUMN IF EXISTS moderated_at;
+ALTER TABLE ads DROP COLUMN IF EXISTS moderated_by;
+ALTER TABLE ads DROP COLUMN IF EXISTS rejection_reason;
+ALTER TABLE ads DROP COLUMN IF EXISTS status;
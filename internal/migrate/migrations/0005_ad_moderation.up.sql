@@ -0,0 +1,10 @@
+-- Существующие объявления уже видны в ленте, поэтому считаются опубликованными;
+-- новые по умолчанию уходят на проверку
+ALTER TABLE ads ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'published'
+    CHECK (status IN ('draft', 'pending_review', 'published', 'rejected', 'archived'));
+ALTER TABLE ads ALTER COLUMN status SET DEFAULT 'pending_review';
+ALTER TABLE ads ADD COLUMN IF NOT EXISTS rejection_reason TEXT;
+ALTER TABLE ads ADD COLUMN IF NOT EXISTS moderated_by INTEGER REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE ads ADD COLUMN IF NOT EXISTS moderated_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS ads_status_created_at_idx ON ads (status, created_at);
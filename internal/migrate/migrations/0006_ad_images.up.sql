@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS ad_images (
+    id SERIAL PRIMARY KEY,
+    ad_id INTEGER NOT NULL REFERENCES ads(id) ON DELETE CASCADE,
+    url VARCHAR(255) NOT NULL,
+    position INTEGER NOT NULL CHECK (position >= 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    -- отложенная проверка позволяет переставлять изображения в одной транзакции
+    CONSTRAINT ad_images_ad_id_position_key UNIQUE (ad_id, position) DEFERRABLE INITIALLY DEFERRED
+);
+
+-- ads.image_url остается обложкой (первое изображение галереи), чтобы лента обходилась без JOIN
+INSERT INTO ad_images (ad_id, url, position)
+SELECT id, image_url, 0 FROM ads WHERE image_url <> '';
@@ -0,0 +1,42 @@
+CREATE TABLE IF NOT EXISTS categories (
+    id SERIAL PRIMARY KEY,
+    parent_id INTEGER REFERENCES categories(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    slug VARCHAR(100) UNIQUE NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS categories_parent_id_idx ON categories (parent_id);
+
+-- у старых объявлений категории нет; при удалении категории объявление остается без нее
+ALTER TABLE ads ADD COLUMN IF NOT EXISTS category_id INTEGER REFERENCES categories(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS ads_category_id_idx ON ads (category_id);
+
+-- Базовое дерево категорий; тот же набор использует хранилище в памяти
+INSERT INTO categories (name, slug, position) VALUES
+    ('Электроника', 'electronics', 1),
+    ('Транспорт', 'transport', 2),
+    ('Недвижимость', 'real-estate', 3),
+    ('Дом и сад', 'home-garden', 4),
+    ('Одежда и обувь', 'clothing', 5),
+    ('Хобби и отдых', 'hobby', 6)
+ON CONFLICT (slug) DO NOTHING;
+
+INSERT INTO categories (parent_id, name, slug, position)
+SELECT p.id, c.name, c.slug, c.position
+FROM (VALUES
+    ('electronics', 'Телефоны', 'phones', 1),
+    ('electronics', 'Ноутбуки и компьютеры', 'computers', 2),
+    ('electronics', 'Фото и видео', 'photo-video', 3),
+    ('transport', 'Автомобили', 'cars', 1),
+    ('transport', 'Велосипеды', 'bicycles', 2),
+    ('transport', 'Запчасти', 'auto-parts', 3),
+    ('real-estate', 'Квартиры', 'apartments', 1),
+    ('real-estate', 'Дома', 'houses', 2),
+    ('home-garden', 'Мебель', 'furniture', 1),
+    ('home-garden', 'Бытовая техника', 'appliances', 2),
+    ('hobby', 'Спорт', 'sports', 1),
+    ('hobby', 'Книги', 'books', 2)
+) AS c (parent_slug, name, slug, position)
+JOIN categories p ON p.slug = c.parent_slug
+ON CONFLICT (slug) DO NOTHING;
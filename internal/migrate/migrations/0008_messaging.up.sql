@@ -0,0 +1,27 @@
+-- Переписка покупателя с автором по конкретному объявлению: одна ветка на пару (объявление, покупатель)
+CREATE TABLE IF NOT EXISTS threads (
+    id SERIAL PRIMARY KEY,
+    ad_id INTEGER NOT NULL REFERENCES ads(id) ON DELETE CASCADE,
+    buyer_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    seller_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- ID последнего прочитанного сообщения каждой стороной; непрочитанные — чужие сообщения с большим ID
+    buyer_last_read_id INTEGER NOT NULL DEFAULT 0,
+    seller_last_read_id INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_message_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT threads_ad_id_buyer_id_key UNIQUE (ad_id, buyer_id),
+    CONSTRAINT threads_not_self CHECK (buyer_id <> seller_id)
+);
+
+CREATE INDEX IF NOT EXISTS threads_buyer_id_idx ON threads (buyer_id, last_message_at DESC);
+CREATE INDEX IF NOT EXISTS threads_seller_id_idx ON threads (seller_id, last_message_at DESC);
+
+CREATE TABLE IF NOT EXISTS messages (
+    id SERIAL PRIMARY KEY,
+    thread_id INTEGER NOT NULL REFERENCES threads(id) ON DELETE CASCADE,
+    sender_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    body TEXT NOT NULL CHECK (char_length(body) BETWEEN 1 AND 2000),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS messages_thread_id_idx ON messages (thread_id, id);
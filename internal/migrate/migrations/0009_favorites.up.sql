@@ -0,0 +1,10 @@
+-- Избранное пользователя; записи удаляются вместе с объявлением или пользователем
+CREATE TABLE IF NOT EXISTS favorites (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    ad_id INTEGER NOT NULL REFERENCES ads(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, ad_id)
+);
+
+-- для favorites_count и выборки тех, кто добавил объявление в избранное
+CREATE INDEX IF NOT EXISTS favorites_ad_id_idx ON favorites (ad_id);
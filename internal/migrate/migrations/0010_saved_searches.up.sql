@@ -0,0 +1,32 @@
+-- Сохраненные поиски: параметры ленты, по которым пользователь ждет новые объявления
+CREATE TABLE IF NOT EXISTS saved_searches (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL CHECK (char_length(name) >= 1),
+    query VARCHAR(200) NOT NULL DEFAULT '',
+    min_price DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    max_price DECIMAL(10, 2) NOT NULL,
+    category_id INTEGER REFERENCES categories(id) ON DELETE CASCADE,
+    sort_by VARCHAR(20) NOT NULL DEFAULT '',
+    sort_order VARCHAR(4) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS saved_searches_user_id_idx ON saved_searches (user_id, id);
+-- кандидаты для нового объявления отбираются по цене
+CREATE INDEX IF NOT EXISTS saved_searches_price_idx ON saved_searches (min_price, max_price);
+
+-- Входящие уведомления пользователя; при удалении поиска уведомления остаются
+CREATE TABLE IF NOT EXISTS notifications (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(30) NOT NULL,
+    ad_id INTEGER NOT NULL REFERENCES ads(id) ON DELETE CASCADE,
+    saved_search_id INTEGER REFERENCES saved_searches(id) ON DELETE SET NULL,
+    read_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS notifications_user_id_idx ON notifications (user_id, id DESC);
+-- объявление попадает в уведомления по одному поиску только один раз, даже если его публикуют повторно
+CREATE UNIQUE INDEX IF NOT EXISTS notifications_saved_search_ad_key ON notifications (saved_search_id, ad_id) WHERE saved_search_id IS NOT NULL;
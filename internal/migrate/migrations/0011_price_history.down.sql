@@ -0,0 +1,5 @@
+ALTER TABLE notifications DROP COLUMN IF EXISTS new_price;
+ALTER TABLE notifications DROP COLUMN IF EXISTS old_price;
+ALTER TABLE favorites DROP COLUMN IF EXISTS price_threshold;
+ALTER TABLE favorites DROP COLUMN IF EXISTS last_seen_price;
+DROP TABLE IF EXISTS ad_price_history;
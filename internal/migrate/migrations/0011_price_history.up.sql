@@ -0,0 +1,25 @@
+-- История цены объявления: первая запись — цена при создании, далее по записи на каждое изменение
+CREATE TABLE IF NOT EXISTS ad_price_history (
+    id SERIAL PRIMARY KEY,
+    ad_id INTEGER NOT NULL REFERENCES ads(id) ON DELETE CASCADE,
+    price DECIMAL(10, 2) NOT NULL CHECK (price >= 0),
+    changed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS ad_price_history_ad_id_idx ON ad_price_history (ad_id, id);
+
+INSERT INTO ad_price_history (ad_id, price, changed_at)
+SELECT id, price, created_at FROM ads;
+
+-- Цена, которую пользователь видел последней (при добавлении в избранное или в последнем уведомлении),
+-- и необязательный порог, ниже которого он ждет уведомление
+ALTER TABLE favorites ADD COLUMN IF NOT EXISTS last_seen_price DECIMAL(10, 2);
+ALTER TABLE favorites ADD COLUMN IF NOT EXISTS price_threshold DECIMAL(10, 2) CHECK (price_threshold >= 0);
+
+UPDATE favorites f SET last_seen_price = a.price FROM ads a WHERE a.id = f.ad_id;
+
+ALTER TABLE favorites ALTER COLUMN last_seen_price SET NOT NULL;
+
+-- цены до и после снижения для уведомлений ad.price_drop
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS old_price DECIMAL(10, 2);
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS new_price DECIMAL(10, 2);
@@ -1,5 +1,25 @@
 package models
 
+import (
+	"strings"
+	"time"
+)
+
+const (
+	SortByCreatedAt = "created_at"
+	SortByPrice     = "price"
+	SortByRelevance = "relevance"
+)
+
+// Статусы объявления: draft → pending_review → published / rejected → archived
+const (
+	AdStatusDraft         = "draft"
+	AdStatusPendingReview = "pending_review"
+	AdStatusPublished     = "published"
+	AdStatusRejected      = "rejected"
+	AdStatusArchived      = "archived"
+)
+
 type Ad struct {
 	ID          int     `json:"id"`
 	Title       string  `json:"title"`
@@ -10,4 +30,213 @@ type Ad struct {
 	Login       string  `json:"login"`
 	CreatedAt   string  `json:"created_at"`
 	IsOwner     bool    `json:"is_owner,omitempty"`
+	Status      string  `json:"status"`
+	// RejectionReason — причина отклонения модератором
+	RejectionReason string `json:"rejection_reason,omitempty"`
+	// CategoryID — категория объявления; 0 у объявлений без категории
+	CategoryID int `json:"category_id,omitempty"`
+	// IsFavorite, как и IsOwner, считается для пользователя запроса
+	IsFavorite     bool `json:"is_favorite,omitempty"`
+	FavoritesCount int  `json:"favorites_count"`
+	// Images — галерея по порядку; заполняется только для одного объявления, в ленте есть лишь обложка image_url
+	Images []AdImage `json:"images,omitempty"`
+	// CreatedAtTime — точное время создания для keyset-пагинации (CreatedAt округлен до секунд)
+	CreatedAtTime time.Time `json:"-"`
+}
+
+// AdImage — изображение из галереи объявления; первое по position служит обложкой
+type AdImage struct {
+	ID       int    `json:"id"`
+	URL      string `json:"url"`
+	Position int    `json:"position"`
+}
+
+// Category — узел дерева категорий; ParentID == 0 у корневых категорий
+type Category struct {
+	ID       int        `json:"id"`
+	ParentID int        `json:"parent_id,omitempty"`
+	Name     string     `json:"name"`
+	Slug     string     `json:"slug"`
+	Children []Category `json:"children,omitempty"`
+}
+
+// Thread — переписка покупателя с автором объявления.
+// UnreadCount и LastMessage считаются для пользователя, запросившего ветку
+type Thread struct {
+	ID          int      `json:"id"`
+	AdID        int      `json:"ad_id"`
+	AdTitle     string   `json:"ad_title"`
+	BuyerID     int      `json:"buyer_id"`
+	BuyerLogin  string   `json:"buyer_login"`
+	SellerID    int      `json:"seller_id"`
+	SellerLogin string   `json:"seller_login"`
+	LastMessage *Message `json:"last_message,omitempty"`
+	UnreadCount int      `json:"unread_count"`
+	CreatedAt   string   `json:"created_at"`
+}
+
+// Message — сообщение в ветке переписки
+type Message struct {
+	ID        int    `json:"id"`
+	ThreadID  int    `json:"thread_id"`
+	SenderID  int    `json:"sender_id"`
+	Body      string `json:"body"`
+	CreatedAt string `json:"created_at"`
+}
+
+// HasParticipant сообщает, что пользователь — покупатель или продавец в ветке
+func (t Thread) HasParticipant(userID int) bool {
+	return userID != 0 && (t.BuyerID == userID || t.SellerID == userID)
+}
+
+// SavedSearch — сохраненные параметры ленты, по которым пользователь получает уведомления о новых объявлениях
+type SavedSearch struct {
+	ID        int     `json:"id"`
+	UserID    int     `json:"-"`
+	Name      string  `json:"name"`
+	Query     string  `json:"q,omitempty"`
+	MinPrice  float64 `json:"min_price"`
+	MaxPrice  float64 `json:"max_price"`
+	Category  int     `json:"category,omitempty"`
+	SortBy    string  `json:"sort_by,omitempty"`
+	SortOrder string  `json:"sort_order,omitempty"`
+	CreatedAt string  `json:"created_at"`
+}
+
+// Filter возвращает параметры ленты, соответствующие сохраненному поиску
+func (s SavedSearch) Filter() AdsFilter {
+	return AdsFilter{
+		SortBy:    s.SortBy,
+		SortOrder: s.SortOrder,
+		MinPrice:  s.MinPrice,
+		MaxPrice:  s.MaxPrice,
+		Query:     s.Query,
+		Category:  s.Category,
+	}.Normalize()
+}
+
+// Типы уведомлений
+const (
+	NotificationSavedSearchMatch = "saved_search.match"
+	NotificationPriceDrop        = "ad.price_drop"
+)
+
+// Notification — уведомление во входящих пользователя
+type Notification struct {
+	ID            int    `json:"id"`
+	UserID        int    `json:"-"`
+	Type          string `json:"type"`
+	AdID          int    `json:"ad_id"`
+	AdTitle       string `json:"ad_title"`
+	SavedSearchID int    `json:"saved_search_id,omitempty"`
+	// OldPrice и NewPrice заполняются для ad.price_drop
+	OldPrice  *float64 `json:"old_price,omitempty"`
+	NewPrice  *float64 `json:"new_price,omitempty"`
+	Read      bool     `json:"read"`
+	CreatedAt string   `json:"created_at"`
+}
+
+// PricePoint — цена объявления, действовавшая с момента ChangedAt
+type PricePoint struct {
+	Price     float64 `json:"price"`
+	ChangedAt string  `json:"changed_at"`
+}
+
+// PriceWatcher — пользователь, ждущий снижения цены, и цена, которую он видел последней
+type PriceWatcher struct {
+	UserID        int
+	LastSeenPrice float64
+}
+
+// AdUpdate описывает изменения объявления; nil-поля остаются без изменений
+type AdUpdate struct {
+	Title       *string
+	Description *string
+	ImageURL    *string
+	Price       *float64
+	// CategoryID: 0 убирает категорию
+	CategoryID *int
+	// Images заменяет всю галерею; ImageURL без Images заменяет только обложку
+	Images *[]string
+}
+
+// AdUpdateResult — что фактически сохранила правка объявления: статус до и после нее и прежняя цена.
+// Статус решается хранилищем под блокировкой строки, поэтому учитывает решения модератора, принятые во время правки
+type AdUpdateResult struct {
+	PrevStatus      string
+	Status          string
+	RejectionReason string
+	OldPrice        float64
+	// Images — новая галерея; заполняется, только если она заменялась
+	Images []AdImage
+}
+
+// StatusAfterContentChange возвращает статус объявления после правки текста, фото или категории:
+// опубликованное и отклоненное уходят на повторную проверку, остальные статусы не меняются
+func StatusAfterContentChange(status string) string {
+	if status == AdStatusPublished || status == AdStatusRejected {
+		return AdStatusPendingReview
+	}
+	return status
+}
+
+// Viewer — пользователь, для которого строится выборка; UserID == 0 для анонимного запроса.
+// От него зависят персональные поля объявлений (is_owner, is_favorite) и видимость неопубликованных объявлений
+type Viewer struct {
+	UserID int
+	// CanModerate — модератор или администратор: видит объявления в любом статусе
+	CanModerate bool
+}
+
+// AdsFilter — параметры выборки ленты объявлений
+type AdsFilter struct {
+	Page      int
+	PageSize  int
+	SortBy    string
+	SortOrder string
+	MinPrice  float64
+	MaxPrice  float64
+	// Query — поисковая строка по заголовку и описанию
+	Query string
+	// Status выбирает объявления только в этом статусе (очередь модерации).
+	// Пустой — опубликованные плюс собственные объявления Viewer в любом статусе
+	Status string
+	// Category — запрошенная категория; CategoryIDs — она вместе с потомками, по ним и идет выборка
+	Category    int
+	CategoryIDs []int
+	// FavoritesOf оставляет только объявления из избранного этого пользователя
+	FavoritesOf int
+	// AdID оставляет только одно объявление: так проверяется, подходит ли оно под сохраненный поиск
+	AdID int
+	// Cursor включает keyset-пагинацию: Page игнорируется, выборка идет от позиции курсора
+	Cursor *AdCursor
+}
+
+// AdCursor — позиция в ленте: значение ключа сортировки и ID крайнего объявления страницы
+type AdCursor struct {
+	Value string
+	ID    int
+	// Backward — страница перед позицией курсора (prev), иначе после нее (next)
+	Backward bool
+}
+
+// Normalize приводит параметры ленты к допустимым значениям
+func (f AdsFilter) Normalize() AdsFilter {
+	if f.Page < 1 {
+		f.Page = 1
+	}
+	if f.PageSize < 1 || f.PageSize > 100 {
+		f.PageSize = 10
+	}
+	f.Query = strings.TrimSpace(f.Query)
+	switch {
+	case f.SortBy == SortByRelevance && f.Query != "":
+	case f.SortBy == SortByPrice:
+	default:
+		f.SortBy = SortByCreatedAt
+	}
+	if f.SortOrder != "ASC" && f.SortOrder != "DESC" {
+		f.SortOrder = "DESC"
+	}
+	return f
 }
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"restapi/internal/auth"
+	"restapi/internal/storage"
+	"restapi/internal/user"
+)
+
+var (
+	// ErrInvalidRole возвращается для неизвестной роли
+	ErrInvalidRole = errors.New("invalid role")
+	// ErrSelfAction возвращается, если администратор пытается заблокировать или понизить сам себя
+	ErrSelfAction = errors.New("action is not allowed on own account")
+)
+
+// UsersPage — страница списка пользователей для администратора
+type UsersPage struct {
+	Items    []user.User
+	Page     int
+	PageSize int
+	Total    int
+}
+
+// ListUsers возвращает пользователей по возрастанию ID
+func (s *Service) ListUsers(ctx context.Context, page, pageSize int) (UsersPage, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	s.log(ctx).Infof("Listing users, page: %d, pageSize: %d", page, pageSize)
+	users, total, err := s.StorageImpl.ListUsers(ctx, pageSize, (page-1)*pageSize)
+	if err != nil {
+		s.log(ctx).Errorf("Failed to list users: %v", err)
+		return UsersPage{}, err
+	}
+	return UsersPage{Items: users, Page: page, PageSize: pageSize, Total: total}, nil
+}
+
+// SetUserRole назначает пользователю роль; новая роль попадет в токены после следующего входа или обновления
+func (s *Service) SetUserRole(ctx context.Context, actorID, userID int, role string) error {
+	if !auth.ValidRole(role) {
+		return ErrInvalidRole
+	}
+	if actorID == userID && role != auth.RoleAdmin {
+		return ErrSelfAction
+	}
+	s.log(ctx).Infof("User ID %d sets role %q for user ID: %d", actorID, role, userID)
+	if err := s.StorageImpl.SetUserRole(ctx, userID, role); err != nil {
+		if !errors.Is(err, storage.ErrUserNotFound) {
+			s.log(ctx).Errorf("Failed to set user role: %v", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// BanUser блокирует пользователя: вход и обновление токенов запрещаются, refresh-токены отзываются
+func (s *Service) BanUser(ctx context.Context, actorID, userID int) error {
+	if actorID == userID {
+		return ErrSelfAction
+	}
+	s.log(ctx).Infof("User ID %d bans user ID: %d", actorID, userID)
+	return s.setUserBanned(ctx, userID, true)
+}
+
+// UnbanUser снимает блокировку пользователя
+func (s *Service) UnbanUser(ctx context.Context, actorID, userID int) error {
+	s.log(ctx).Infof("User ID %d unbans user ID: %d", actorID, userID)
+	return s.setUserBanned(ctx, userID, false)
+}
+
+func (s *Service) setUserBanned(ctx context.Context, userID int, banned bool) error {
+	if err := s.StorageImpl.SetUserBanned(ctx, userID, banned); err != nil {
+		if !errors.Is(err, storage.ErrUserNotFound) {
+			s.log(ctx).Errorf("Failed to update user ban: %v", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// RemoveAd удаляет любое объявление без проверки авторства
+func (s *Service) RemoveAd(ctx context.Context, actorID, adID int) error {
+	s.log(ctx).Infof("User ID %d removes ad ID: %d", actorID, adID)
+	ad, err := s.StorageImpl.GetAd(ctx, adID)
+	if err == nil {
+		err = s.StorageImpl.DeleteAd(ctx, adID)
+	}
+	if err != nil {
+		if !errors.Is(err, storage.ErrAdNotFound) {
+			s.log(ctx).Errorf("Failed to remove ad: %v", err)
+		}
+		return err
+	}
+	s.publishAdDeleted(ad)
+	return nil
+}
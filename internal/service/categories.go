@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"restapi/internal/models"
+)
+
+// ErrCategoryNotFound возвращается для неизвестного ID категории
+var ErrCategoryNotFound = errors.New("category not found")
+
+// Categories возвращает дерево категорий: корневые категории с вложенными подкатегориями
+func (s *Service) Categories(ctx context.Context) ([]models.Category, error) {
+	s.log(ctx).Infof("Fetching categories")
+	flat, err := s.StorageImpl.GetCategories(ctx)
+	if err != nil {
+		s.log(ctx).Errorf("Failed to get categories: %v", err)
+		return nil, err
+	}
+	return categoryTree(flat, 0), nil
+}
+
+// withCategoryFilter раскрывает filter.Category в список из самой категории и всех ее потомков
+func (s *Service) withCategoryFilter(ctx context.Context, filter models.AdsFilter) (models.AdsFilter, error) {
+	if filter.Category == 0 {
+		return filter, nil
+	}
+	ids, err := s.categorySubtree(ctx, filter.Category)
+	if err != nil {
+		return filter, err
+	}
+	filter.CategoryIDs = ids
+	return filter, nil
+}
+
+// checkCategory проверяет, что категория существует; 0 означает объявление без категории
+func (s *Service) checkCategory(ctx context.Context, categoryID int) error {
+	if categoryID == 0 {
+		return nil
+	}
+	_, err := s.categorySubtree(ctx, categoryID)
+	return err
+}
+
+// categorySubtree возвращает ID категории и всех ее потомков
+func (s *Service) categorySubtree(ctx context.Context, categoryID int) ([]int, error) {
+	flat, err := s.StorageImpl.GetCategories(ctx)
+	if err != nil {
+		s.log(ctx).Errorf("Failed to get categories: %v", err)
+		return nil, err
+	}
+	children := make(map[int][]int, len(flat))
+	found := false
+	for _, c := range flat {
+		children[c.ParentID] = append(children[c.ParentID], c.ID)
+		found = found || c.ID == categoryID
+	}
+	if !found {
+		return nil, ErrCategoryNotFound
+	}
+	ids := []int{categoryID}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, children[ids[i]]...)
+	}
+	return ids, nil
+}
+
+// categoryTree собирает потомков parentID, сохраняя порядок плоского списка
+func categoryTree(flat []models.Category, parentID int) []models.Category {
+	var nodes []models.Category
+	for _, c := range flat {
+		if c.ParentID != parentID {
+			continue
+		}
+		c.Children = categoryTree(flat, c.ID)
+		nodes = append(nodes, c)
+	}
+	return nodes
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"restapi/internal/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidCursor возвращается для поврежденного или подделанного курсора
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrCursorUnsupported возвращается для сортировки по релевантности: у нее нет стабильного ключа
+	ErrCursorUnsupported = errors.New("cursor pagination is not supported for relevance sort")
+)
+
+// cursorPayload — содержимое курсора; сортировка хранится в нем, чтобы порядок не менялся между страницами
+type cursorPayload struct {
+	SortBy    string `json:"s"`
+	SortOrder string `json:"o"`
+	Value     string `json:"v"`
+	ID        int    `json:"i"`
+	Backward  bool   `json:"b,omitempty"`
+}
+
+// GetAdsByCursor возвращает страницу ленты после (или перед) позицией курсора; пустой cursor — первая страница
+func (s *Service) GetAdsByCursor(ctx context.Context, filter models.AdsFilter, cursor string, viewer models.Viewer) (AdsPage, error) {
+	if cursor != "" {
+		payload, err := decodeCursor(cursor)
+		if err != nil {
+			s.log(ctx).Warnf("Rejected ads cursor: %v", err)
+			return AdsPage{}, ErrInvalidCursor
+		}
+		filter.SortBy, filter.SortOrder = payload.SortBy, payload.SortOrder
+		filter.Cursor = &models.AdCursor{Value: payload.Value, ID: payload.ID, Backward: payload.Backward}
+	}
+	filter = filter.Normalize()
+	if filter.SortBy == models.SortByRelevance {
+		return AdsPage{}, ErrCursorUnsupported
+	}
+	filter, err := s.withCategoryFilter(ctx, filter)
+	if err != nil {
+		return AdsPage{}, err
+	}
+
+	ads, err := s.GetAds(ctx, filter, viewer)
+	if err != nil {
+		return AdsPage{}, err
+	}
+	total, err := s.countAds(ctx, filter, viewer)
+	if err != nil {
+		return AdsPage{}, err
+	}
+	page := AdsPage{Items: ads, PageSize: filter.PageSize, Total: total}
+	if len(ads) == 0 {
+		return page, nil
+	}
+
+	backward := filter.Cursor != nil && filter.Cursor.Backward
+	full := len(ads) == filter.PageSize
+	// вперед: следующая страница есть, если текущая заполнена; назад есть, если мы пришли по курсору.
+	// при движении назад — наоборот
+	hasNext, hasPrev := full, filter.Cursor != nil
+	if backward {
+		hasNext, hasPrev = true, full
+	}
+	if hasNext {
+		page.NextCursor = encodeCursor(cursorFor(filter, ads[len(ads)-1], false))
+	}
+	if hasPrev {
+		page.PrevCursor = encodeCursor(cursorFor(filter, ads[0], true))
+	}
+	return page, nil
+}
+
+func cursorFor(filter models.AdsFilter, ad models.Ad, backward bool) cursorPayload {
+	value := ad.CreatedAtTime.UTC().Format(time.RFC3339Nano)
+	if filter.SortBy == models.SortByPrice {
+		value = strconv.FormatFloat(ad.Price, 'f', -1, 64)
+	}
+	return cursorPayload{
+		SortBy:    filter.SortBy,
+		SortOrder: filter.SortOrder,
+		Value:     value,
+		ID:        ad.ID,
+		Backward:  backward,
+	}
+}
+
+// encodeCursor сериализует позицию в base64url и подписывает HMAC, чтобы клиент не мог ее подменить
+func encodeCursor(p cursorPayload) string {
+	data, _ := json.Marshal(p)
+	body := base64.RawURLEncoding.EncodeToString(data)
+	return body + "." + base64.RawURLEncoding.EncodeToString(signCursor(body))
+}
+
+func decodeCursor(cursor string) (cursorPayload, error) {
+	var p cursorPayload
+	body, sig, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return p, errors.New("malformed cursor")
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, signCursor(body)) {
+		return p, errors.New("bad cursor signature")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return p, err
+	}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return p, err
+	}
+	return p, nil
+}
+
+func signCursor(body string) []byte {
+	// отдельный ключ, производный от JWTKey, чтобы подпись курсора нельзя было использовать как JWT-подпись
+	key := sha256.Sum256([]byte("ads-cursor:" + JWTKey))
+	mac := hmac.New(sha256.New, key[:])
+	mac.Write([]byte(body))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"restapi/internal/hasher"
+	"restapi/internal/models"
+	"restapi/internal/storage"
+	"strings"
+	"testing"
+)
+
+// createPublishedAds создает опубликованные объявления с указанными ценами и возвращает их ID
+func createPublishedAds(t *testing.T, store *storage.StorageMemory, userID int, prices ...float64) []int {
+	t.Helper()
+	ids := make([]int, 0, len(prices))
+	for i, price := range prices {
+		id, err := store.CreateAd(context.Background(), userID, fmt.Sprintf("ad %d", i), "test description", 0,
+			[]string{"https://example.com/a.jpg"}, price, models.AdStatusPublished)
+		if err != nil {
+			t.Fatalf("CreateAd: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	JWTKey = "test-key"
+	want := cursorPayload{SortBy: models.SortByPrice, SortOrder: "ASC", Value: "100", ID: 7, Backward: true}
+	got, err := decodeCursor(encodeCursor(want))
+	if err != nil {
+		t.Fatalf("decodeCursor: %v", err)
+	}
+	if got != want {
+		t.Fatalf("decodeCursor = %+v, want %+v", got, want)
+	}
+}
+
+func TestCursorTamperRejected(t *testing.T) {
+	svc, _ := newTestService(t, hasher.Bcrypt)
+	valid := encodeCursor(cursorPayload{SortBy: models.SortByCreatedAt, SortOrder: "DESC", Value: "2024-01-01T00:00:00Z", ID: 10})
+	body, sig, _ := strings.Cut(valid, ".")
+	forged, _ := json.Marshal(cursorPayload{SortBy: models.SortByCreatedAt, SortOrder: "DESC", Value: "2024-01-01T00:00:00Z", ID: 1})
+	JWTKey = "other-key"
+	otherKey := encodeCursor(cursorPayload{SortBy: models.SortByCreatedAt, SortOrder: "DESC", ID: 10})
+	JWTKey = "test-key"
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"payload replaced", base64.RawURLEncoding.EncodeToString(forged) + "." + sig},
+		{"signature replaced", body + "." + base64.RawURLEncoding.EncodeToString([]byte("signature"))},
+		{"signature missing", body},
+		{"signature not base64", body + ".!!!"},
+		{"signed with another key", otherKey},
+		{"garbage", "abc.def"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.GetAdsByCursor(context.Background(), models.AdsFilter{}, tt.cursor, models.Viewer{})
+			if !errors.Is(err, ErrInvalidCursor) {
+				t.Fatalf("GetAdsByCursor = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
+
+func TestCursorPagesHaveNoDuplicatesOrGaps(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter models.AdsFilter
+	}{
+		{"newest first", models.AdsFilter{PageSize: 3}},
+		{"oldest first", models.AdsFilter{PageSize: 3, SortOrder: "ASC"}},
+		// одинаковые цены проверяют, что позиция учитывает ID, а не только цену
+		{"price ascending with ties", models.AdsFilter{PageSize: 3, SortBy: models.SortByPrice, SortOrder: "ASC"}},
+		{"price descending with ties", models.AdsFilter{PageSize: 3, SortBy: models.SortByPrice, SortOrder: "DESC"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			svc, store := newTestService(t, hasher.Bcrypt)
+			userID, err := store.RegisterUser(ctx, "seller", "hash")
+			if err != nil {
+				t.Fatalf("RegisterUser: %v", err)
+			}
+			ids := createPublishedAds(t, store, userID, 100, 200, 200, 200, 300, 300, 400, 500)
+			filter := tt.filter
+			filter.MaxPrice = 1000
+
+			var pages [][]int
+			seen := map[int]bool{}
+			// cursor — курсор последней непустой страницы; next — следующей запрашиваемой
+			cursor, next := "", ""
+			for i := 0; ; i++ {
+				if i > len(ids) {
+					t.Fatal("pagination does not terminate")
+				}
+				page, err := svc.GetAdsByCursor(ctx, filter, next, models.Viewer{})
+				if err != nil {
+					t.Fatalf("GetAdsByCursor: %v", err)
+				}
+				// заполненная последняя страница тоже отдает next_cursor, за которым пусто
+				if len(page.Items) == 0 {
+					break
+				}
+				cursor = next
+				var pageIDs []int
+				for _, ad := range page.Items {
+					if seen[ad.ID] {
+						t.Fatalf("ad %d returned twice", ad.ID)
+					}
+					seen[ad.ID] = true
+					pageIDs = append(pageIDs, ad.ID)
+				}
+				pages = append(pages, pageIDs)
+				if i == 0 {
+					// новое объявление между запросами не должно сдвигать следующие страницы
+					createPublishedAds(t, store, userID, 250)
+				}
+				if page.NextCursor == "" {
+					break
+				}
+				next = page.NextCursor
+			}
+			for _, id := range ids {
+				if !seen[id] {
+					t.Fatalf("ad %d was skipped, pages: %v", id, pages)
+				}
+			}
+
+			// обратный проход по prev_cursor возвращает те же страницы
+			page, err := svc.GetAdsByCursor(ctx, filter, cursor, models.Viewer{})
+			if err != nil {
+				t.Fatalf("GetAdsByCursor: %v", err)
+			}
+			for i := len(pages) - 2; i >= 0; i-- {
+				if page.PrevCursor == "" {
+					t.Fatalf("page %d has no prev cursor", i+1)
+				}
+				page, err = svc.GetAdsByCursor(ctx, filter, page.PrevCursor, models.Viewer{})
+				if err != nil {
+					t.Fatalf("GetAdsByCursor backward: %v", err)
+				}
+				var pageIDs []int
+				for _, ad := range page.Items {
+					pageIDs = append(pageIDs, ad.ID)
+				}
+				if fmt.Sprint(pageIDs) != fmt.Sprint(pages[i]) {
+					t.Fatalf("page %d backward = %v, forward = %v", i, pageIDs, pages[i])
+				}
+			}
+		})
+	}
+}
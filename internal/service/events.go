@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"restapi/internal/events"
+	"restapi/internal/models"
+)
+
+// publish рассылает событие, если поток событий включен
+func (s *Service) publish(ev events.Event) {
+	if s.Events != nil {
+		s.Events.Publish(ev)
+	}
+}
+
+// adAudience повторяет правила видимости GetAd: опубликованное видят все, остальное — автор и модераторы
+func adAudience(ad models.Ad) events.Audience {
+	if ad.Status == models.AdStatusPublished {
+		return events.Audience{All: true}
+	}
+	return events.Audience{UserIDs: []int{ad.UserID}, Moderators: true}
+}
+
+// publishAd рассылает объявление тем, кто его видит. Если оно было в ленте и пропало из нее,
+// остальные получают ad.unpublished без содержимого
+func (s *Service) publishAd(eventType string, ad models.Ad, wasPublished bool) {
+	// is_owner зависит от получателя, поэтому в общее событие не попадает
+	ad.IsOwner = false
+	s.publish(events.Event{Type: eventType, Data: ad, Audience: adAudience(ad)})
+	if wasPublished && ad.Status != models.AdStatusPublished {
+		s.publish(events.Event{Type: events.AdUnpublished, Data: events.AdRef{ID: ad.ID}, Audience: events.Audience{All: true}})
+	}
+}
+
+// publishAdByID перечитывает объявление после изменения и рассылает его; ошибка не влияет на сам запрос
+func (s *Service) publishAdByID(ctx context.Context, eventType string, adID int, wasPublished bool) {
+	if s.Events == nil {
+		return
+	}
+	ad, err := s.StorageImpl.GetAd(ctx, adID)
+	if err != nil {
+		s.log(ctx).Warnf("Failed to load ad ID %d for %s event: %v", adID, eventType, err)
+		return
+	}
+	s.publishAd(eventType, ad, wasPublished)
+}
+
+// publishAdDeleted сообщает об удалении тем, кто видел объявление
+func (s *Service) publishAdDeleted(ad models.Ad) {
+	s.publish(events.Event{Type: events.AdDeleted, Data: events.AdRef{ID: ad.ID}, Audience: adAudience(ad)})
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"restapi/internal/models"
+)
+
+// AddFavorite добавляет объявление в избранное пользователя; добавить можно только видимое ему объявление.
+// priceThreshold — необязательный порог: уведомления о снижении цены приходят, только когда цена не выше его
+func (s *Service) AddFavorite(ctx context.Context, userID, adID int, priceThreshold *float64) error {
+	s.log(ctx).Infof("Adding ad ID %d to favorites of user ID: %d", adID, userID)
+	if _, err := s.GetAd(ctx, adID, models.Viewer{UserID: userID}); err != nil {
+		return err
+	}
+	if err := s.StorageImpl.AddFavorite(ctx, userID, adID, priceThreshold); err != nil {
+		s.log(ctx).Errorf("Failed to add favorite: %v", err)
+		return err
+	}
+	return nil
+}
+
+// RemoveFavorite убирает объявление из избранного пользователя; повторное удаление не ошибка
+func (s *Service) RemoveFavorite(ctx context.Context, userID, adID int) error {
+	s.log(ctx).Infof("Removing ad ID %d from favorites of user ID: %d", adID, userID)
+	if err := s.StorageImpl.RemoveFavorite(ctx, userID, adID); err != nil {
+		s.log(ctx).Errorf("Failed to remove favorite: %v", err)
+		return err
+	}
+	return nil
+}
+
+// PriceHistory возвращает историю цены объявления; доступна тем же, кто видит само объявление
+func (s *Service) PriceHistory(ctx context.Context, adID int, viewer models.Viewer) ([]models.PricePoint, error) {
+	if _, err := s.GetAd(ctx, adID, viewer); err != nil {
+		return nil, err
+	}
+	history, err := s.StorageImpl.GetPriceHistory(ctx, adID)
+	if err != nil {
+		s.log(ctx).Errorf("Failed to get price history: %v", err)
+		return nil, err
+	}
+	return history, nil
+}
+
+// notifyPriceDrop уведомляет добавивших опубликованное объявление в избранное о снижении цены
+// относительно той, что каждый из них видел последней.
+// Ошибки только логируются: правка объявления уже сохранена
+func (s *Service) notifyPriceDrop(ctx context.Context, ad models.Ad) {
+	watchers, err := s.StorageImpl.ClaimPriceDropWatchers(ctx, ad.ID, ad.Price)
+	if err != nil {
+		s.log(ctx).Errorf("Failed to find price drop watchers: %v", err)
+		return
+	}
+	newPrice := ad.Price
+	for _, w := range watchers {
+		if w.UserID == ad.UserID {
+			continue
+		}
+		oldPrice := w.LastSeenPrice
+		s.notify(ctx, models.Notification{
+			UserID:   w.UserID,
+			Type:     models.NotificationPriceDrop,
+			AdID:     ad.ID,
+			OldPrice: &oldPrice,
+			NewPrice: &newPrice,
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"restapi/internal/hasher"
+	"restapi/internal/models"
+	"testing"
+)
+
+func TestClaimPriceDropWatchers(t *testing.T) {
+	threshold := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name      string
+		threshold *float64
+		// prices — цены объявления по очереди после добавления в избранное по цене 1000
+		prices []float64
+		// want — увиденная цена у каждого срабатывания, по порядку
+		want []float64
+	}{
+		{"single drop", nil, []float64{900}, []float64{1000}},
+		{"raise is ignored", nil, []float64{1100}, nil},
+		{"every drop below the last seen", nil, []float64{900, 800}, []float64{1000, 900}},
+		{"raise and return is not a new drop", nil, []float64{900, 1000, 900}, []float64{1000}},
+		{"drop below the previous low", nil, []float64{900, 1000, 850}, []float64{1000, 900}},
+		{"above threshold", threshold(700), []float64{900, 800}, nil},
+		{"reaches threshold", threshold(700), []float64{900, 700}, []float64{1000}},
+		{"below threshold then lower", threshold(700), []float64{600, 500}, []float64{1000, 600}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			_, store := newTestService(t, hasher.Bcrypt)
+			sellerID, _ := store.RegisterUser(ctx, "seller", "hash")
+			buyerID, _ := store.RegisterUser(ctx, "buyer", "hash")
+			ids := createPublishedAds(t, store, sellerID, 1000)
+			if err := store.AddFavorite(ctx, buyerID, ids[0], tt.threshold); err != nil {
+				t.Fatalf("AddFavorite: %v", err)
+			}
+
+			var got []float64
+			for _, price := range tt.prices {
+				watchers, err := store.ClaimPriceDropWatchers(ctx, ids[0], price)
+				if err != nil {
+					t.Fatalf("ClaimPriceDropWatchers: %v", err)
+				}
+				for _, w := range watchers {
+					if w.UserID != buyerID {
+						t.Fatalf("watcher = %+v, want user %d", w, buyerID)
+					}
+					got = append(got, w.LastSeenPrice)
+				}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("last seen prices = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApproveAdNotifiesPriceDrop(t *testing.T) {
+	ctx := context.Background()
+	svc, store := newTestService(t, hasher.Bcrypt)
+	sellerID, _ := store.RegisterUser(ctx, "seller", "hash")
+	buyerID, _ := store.RegisterUser(ctx, "buyer", "hash")
+	moderatorID, _ := store.RegisterUser(ctx, "moderator", "hash")
+	ids := createPublishedAds(t, store, sellerID, 1000)
+	if err := svc.AddFavorite(ctx, buyerID, ids[0], nil); err != nil {
+		t.Fatalf("AddFavorite: %v", err)
+	}
+
+	// новый заголовок отправляет объявление на проверку, и о снижении цены пока не сообщается
+	title, price := "Updated title", 800.0
+	ad, err := svc.UpdateAd(ctx, sellerID, ids[0], models.AdUpdate{Title: &title, Price: &price})
+	if err != nil {
+		t.Fatalf("UpdateAd: %v", err)
+	}
+	if ad.Status != models.AdStatusPendingReview {
+		t.Fatalf("status = %s, want %s", ad.Status, models.AdStatusPendingReview)
+	}
+	if page, _ := svc.Notifications(ctx, buyerID, false, 1, 10); page.Total != 0 {
+		t.Fatalf("notifications before approval = %+v, want none", page.Items)
+	}
+
+	if err := svc.ApproveAd(ctx, moderatorID, ids[0]); err != nil {
+		t.Fatalf("ApproveAd: %v", err)
+	}
+	page, err := svc.Notifications(ctx, buyerID, false, 1, 10)
+	if err != nil {
+		t.Fatalf("Notifications: %v", err)
+	}
+	if page.Total != 1 {
+		t.Fatalf("notifications = %+v, want one", page.Items)
+	}
+	n := page.Items[0]
+	if n.Type != models.NotificationPriceDrop || *n.OldPrice != 1000 || *n.NewPrice != 800 {
+		t.Fatalf("notification = %+v, want price drop 1000 -> 800", n)
+	}
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"restapi/internal/events"
+	"restapi/internal/models"
+	"restapi/internal/storage"
+	"slices"
+)
+
+// MaxAdImages — максимальное число изображений в галерее объявления
+const MaxAdImages = 10
+
+var (
+	// ErrTooManyImages возвращается при попытке добавить изображение в заполненную галерею
+	ErrTooManyImages = errors.New("too many ad images")
+	// ErrLastImage возвращается при попытке удалить единственное изображение объявления
+	ErrLastImage = errors.New("ad must have at least one image")
+	// ErrInvalidImageOrder возвращается, если новый порядок не перечисляет всю галерею ровно по одному разу
+	ErrInvalidImageOrder = errors.New("image order must list every ad image exactly once")
+)
+
+// AddAdImage добавляет изображение в конец галереи объявления автора.
+// Новое фото, как и правка текста, отправляет опубликованное или отклоненное объявление на повторную проверку
+func (s *Service) AddAdImage(ctx context.Context, userID, adID int, url string) (models.AdImage, error) {
+	s.log(ctx).Infof("Adding image to ad ID %d by user ID: %d", adID, userID)
+	ad, err := s.editableAd(ctx, userID, adID)
+	if err != nil {
+		return models.AdImage{}, err
+	}
+	// лимит и смена статуса проверяются хранилищем в одной транзакции, чтобы параллельные запросы не превысили лимит
+	img, err := s.StorageImpl.AddAdImage(ctx, adID, url, MaxAdImages)
+	switch {
+	case errors.Is(err, storage.ErrAdImageLimit):
+		return models.AdImage{}, ErrTooManyImages
+	case errors.Is(err, storage.ErrAdNotFound), errors.Is(err, storage.ErrAdStatusConflict):
+		return models.AdImage{}, err
+	case err != nil:
+		s.log(ctx).Errorf("Failed to add ad image: %v", err)
+		return models.AdImage{}, err
+	}
+	s.publishAdByID(ctx, events.AdUpdated, adID, ad.Status == models.AdStatusPublished)
+	return img, nil
+}
+
+// RemoveAdImage удаляет изображение из галереи объявления автора; последнее изображение удалить нельзя.
+// Опубликованное или отклоненное объявление уходит на повторную проверку
+func (s *Service) RemoveAdImage(ctx context.Context, userID, adID, imageID int) error {
+	s.log(ctx).Infof("Removing image ID %d from ad ID %d by user ID: %d", imageID, adID, userID)
+	ad, err := s.editableAd(ctx, userID, adID)
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(ad.Images, func(img models.AdImage) bool { return img.ID == imageID }) {
+		return storage.ErrAdImageNotFound
+	}
+	if len(ad.Images) == 1 {
+		return ErrLastImage
+	}
+	// смена статуса выполняется хранилищем в той же транзакции, что и удаление
+	err = s.StorageImpl.DeleteAdImage(ctx, adID, imageID)
+	switch {
+	case errors.Is(err, storage.ErrAdNotFound), errors.Is(err, storage.ErrAdImageNotFound), errors.Is(err, storage.ErrAdStatusConflict):
+		return err
+	case err != nil:
+		s.log(ctx).Errorf("Failed to delete ad image: %v", err)
+		return err
+	}
+	s.publishAdByID(ctx, events.AdUpdated, adID, ad.Status == models.AdStatusPublished)
+	return nil
+}
+
+// ReorderAdImages задает новый порядок галереи; первое изображение становится обложкой в ленте.
+// Измененный порядок отправляет опубликованное или отклоненное объявление на повторную проверку
+func (s *Service) ReorderAdImages(ctx context.Context, userID, adID int, imageIDs []int) ([]models.AdImage, error) {
+	s.log(ctx).Infof("Reordering images of ad ID %d by user ID: %d", adID, userID)
+	ad, err := s.editableAd(ctx, userID, adID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]models.AdImage, len(ad.Images))
+	for _, img := range ad.Images {
+		byID[img.ID] = img
+	}
+	if len(imageIDs) != len(ad.Images) {
+		return nil, ErrInvalidImageOrder
+	}
+	images := make([]models.AdImage, 0, len(imageIDs))
+	for i, id := range imageIDs {
+		img, ok := byID[id]
+		if !ok {
+			return nil, ErrInvalidImageOrder
+		}
+		delete(byID, id)
+		img.Position = i
+		images = append(images, img)
+	}
+	// новый порядок меняет обложку, поэтому хранилище отправляет объявление на повторную проверку
+	err = s.StorageImpl.ReorderAdImages(ctx, adID, imageIDs)
+	switch {
+	case errors.Is(err, storage.ErrAdImageNotFound):
+		return nil, ErrInvalidImageOrder
+	case errors.Is(err, storage.ErrAdNotFound), errors.Is(err, storage.ErrAdStatusConflict):
+		return nil, err
+	case err != nil:
+		s.log(ctx).Errorf("Failed to reorder ad images: %v", err)
+		return nil, err
+	}
+	s.publishAdByID(ctx, events.AdUpdated, adID, ad.Status == models.AdStatusPublished)
+	return images, nil
+}
+
+// editableAd загружает объявление автора, галерею которого еще можно менять
+func (s *Service) editableAd(ctx context.Context, userID, adID int) (models.Ad, error) {
+	ad, err := s.ownedAd(ctx, userID, adID)
+	if err != nil {
+		return models.Ad{}, err
+	}
+	if ad.Status == models.AdStatusArchived {
+		return models.Ad{}, storage.ErrAdStatusConflict
+	}
+	return ad, nil
+}
+
+func imageURLs(images []models.AdImage) []string {
+	urls := make([]string, 0, len(images))
+	for _, img := range images {
+		urls = append(urls, img.URL)
+	}
+	return urls
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"restapi/internal/hasher"
+	"restapi/internal/models"
+	"restapi/internal/storage"
+	"sync"
+	"testing"
+)
+
+func TestAddAdImageLimitUnderConcurrency(t *testing.T) {
+	ctx := context.Background()
+	svc, store := newTestService(t, hasher.Bcrypt)
+	sellerID, _ := store.RegisterUser(ctx, "seller", "hash")
+	ids := createPublishedAds(t, store, sellerID, 100)
+
+	const attempts = 3 * MaxAdImages
+	var wg sync.WaitGroup
+	errs := make(chan error, attempts)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := svc.AddAdImage(ctx, sellerID, ids[0], fmt.Sprintf("https://example.com/%d.jpg", i))
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	added := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			added++
+		case !errors.Is(err, ErrTooManyImages):
+			t.Fatalf("AddAdImage: %v", err)
+		}
+	}
+	// у объявления уже было одно изображение
+	if added != MaxAdImages-1 {
+		t.Fatalf("added %d images, want %d", added, MaxAdImages-1)
+	}
+	ad, err := store.GetAd(ctx, ids[0])
+	if err != nil {
+		t.Fatalf("GetAd: %v", err)
+	}
+	if len(ad.Images) != MaxAdImages {
+		t.Fatalf("gallery has %d images, want %d", len(ad.Images), MaxAdImages)
+	}
+}
+
+func TestAddAdImageStatus(t *testing.T) {
+	tests := []struct {
+		status string
+		want   string
+		err    error
+	}{
+		{models.AdStatusDraft, models.AdStatusDraft, nil},
+		{models.AdStatusPendingReview, models.AdStatusPendingReview, nil},
+		{models.AdStatusPublished, models.AdStatusPendingReview, nil},
+		{models.AdStatusRejected, models.AdStatusPendingReview, nil},
+		{models.AdStatusArchived, models.AdStatusArchived, storage.ErrAdStatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			ctx := context.Background()
+			svc, store := newTestService(t, hasher.Bcrypt)
+			sellerID, _ := store.RegisterUser(ctx, "seller", "hash")
+			adID, err := store.CreateAd(ctx, sellerID, "Mountain bike", "Bike in good condition", 0,
+				[]string{"https://example.com/a.jpg"}, 300, tt.status)
+			if err != nil {
+				t.Fatalf("CreateAd: %v", err)
+			}
+			if _, err := svc.AddAdImage(ctx, sellerID, adID, "https://example.com/b.jpg"); !errors.Is(err, tt.err) {
+				t.Fatalf("AddAdImage = %v, want %v", err, tt.err)
+			}
+			ad, err := store.GetAd(ctx, adID)
+			if err != nil {
+				t.Fatalf("GetAd: %v", err)
+			}
+			if ad.Status != tt.want {
+				t.Fatalf("status = %s, want %s", ad.Status, tt.want)
+			}
+		})
+	}
+}
+
+func TestGalleryChangesSendAdToReview(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(svc *Service, sellerID int, ad models.Ad) error
+		want   string
+	}{
+		{"delete", func(svc *Service, sellerID int, ad models.Ad) error {
+			return svc.RemoveAdImage(context.Background(), sellerID, ad.ID, ad.Images[0].ID)
+		}, models.AdStatusPendingReview},
+		{"reorder", func(svc *Service, sellerID int, ad models.Ad) error {
+			_, err := svc.ReorderAdImages(context.Background(), sellerID, ad.ID, []int{ad.Images[1].ID, ad.Images[0].ID})
+			return err
+		}, models.AdStatusPendingReview},
+		{"same order", func(svc *Service, sellerID int, ad models.Ad) error {
+			_, err := svc.ReorderAdImages(context.Background(), sellerID, ad.ID, []int{ad.Images[0].ID, ad.Images[1].ID})
+			return err
+		}, models.AdStatusPublished},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			svc, store := newTestService(t, hasher.Bcrypt)
+			sellerID, _ := store.RegisterUser(ctx, "seller", "hash")
+			adID, err := store.CreateAd(ctx, sellerID, "Mountain bike", "Bike in good condition", 0,
+				[]string{"https://example.com/a.jpg", "https://example.com/b.jpg"}, 300, models.AdStatusPublished)
+			if err != nil {
+				t.Fatalf("CreateAd: %v", err)
+			}
+			ad, err := store.GetAd(ctx, adID)
+			if err != nil {
+				t.Fatalf("GetAd: %v", err)
+			}
+			if err := tt.change(svc, sellerID, ad); err != nil {
+				t.Fatalf("change: %v", err)
+			}
+			if ad, err = store.GetAd(ctx, adID); err != nil {
+				t.Fatalf("GetAd: %v", err)
+			}
+			if ad.Status != tt.want {
+				t.Fatalf("status = %s, want %s", ad.Status, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"restapi/internal/migrate"
+	"time"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+	StatusDraining    = "draining"
+)
+
+// Тексты ошибок проверок готовности фиксированы: /readyz доступен без авторизации,
+// а подробности (адреса, сообщения драйвера БД) пишутся только в лог
+const (
+	checkErrUnavailable           = "unavailable"
+	checkErrSchemaVersionMismatch = "schema version mismatch"
+)
+
+// CheckResult — результат одной проверки готовности
+type CheckResult struct {
+	Status          string `json:"status"`
+	Error           string `json:"error,omitempty"`
+	LatencyMS       int64  `json:"latency_ms,omitempty"`
+	CurrentVersion  *int   `json:"current_version,omitempty"`
+	ExpectedVersion *int   `json:"expected_version,omitempty"`
+}
+
+// Liveness — ответ /healthz
+type Liveness struct {
+	Status        string `json:"status"`
+	UptimeSeconds int64  `json:"uptime_seconds"`
+}
+
+// Readiness — ответ /readyz
+type Readiness struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// Liveness сообщает, что процесс жив; зависимости не проверяются
+func (s *Service) Liveness() Liveness {
+	return Liveness{
+		Status:        StatusOK,
+		UptimeSeconds: int64(time.Since(s.startedAt).Seconds()),
+	}
+}
+
+// Readiness проверяет БД, версию схемы и признак завершения работы
+func (s *Service) Readiness(ctx context.Context) (Readiness, bool) {
+	checks := make(map[string]CheckResult, 3)
+	ready := true
+
+	start := time.Now()
+	if err := s.StorageImpl.Ping(ctx); err != nil {
+		s.log(ctx).Warnf("Readiness: database ping failed: %v", err)
+		checks["database"] = CheckResult{Status: StatusUnavailable, Error: checkErrUnavailable}
+		ready = false
+	} else {
+		checks["database"] = CheckResult{Status: StatusOK, LatencyMS: time.Since(start).Milliseconds()}
+	}
+
+	expected := migrate.Latest()
+	current, err := s.StorageImpl.SchemaVersion(ctx)
+	switch {
+	case err != nil:
+		s.log(ctx).Warnf("Readiness: failed to read schema version: %v", err)
+		checks["migrations"] = CheckResult{Status: StatusUnavailable, Error: checkErrUnavailable, ExpectedVersion: &expected}
+		ready = false
+	case current < expected:
+		s.log(ctx).Warnf("Readiness: schema version %d is behind %d", current, expected)
+		checks["migrations"] = CheckResult{
+			Status:          StatusUnavailable,
+			Error:           checkErrSchemaVersionMismatch,
+			CurrentVersion:  &current,
+			ExpectedVersion: &expected,
+		}
+		ready = false
+	default:
+		checks["migrations"] = CheckResult{Status: StatusOK, CurrentVersion: &current, ExpectedVersion: &expected}
+	}
+
+	if s.draining.Load() {
+		checks["shutdown"] = CheckResult{Status: StatusDraining}
+		ready = false
+	} else {
+		checks["shutdown"] = CheckResult{Status: StatusOK}
+	}
+
+	status := StatusOK
+	if !ready {
+		status = StatusUnavailable
+	}
+	return Readiness{Status: status, Checks: checks}, ready
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"restapi/internal/hasher"
+	"restapi/internal/migrate"
+	"restapi/internal/storage"
+	"strings"
+	"testing"
+)
+
+// brokenStorage возвращает заданные ошибки проверок готовности
+type brokenStorage struct {
+	storage.Storage
+	pingErr error
+	version int
+	versErr error
+}
+
+func (b brokenStorage) Ping(ctx context.Context) error { return b.pingErr }
+
+func (b brokenStorage) SchemaVersion(ctx context.Context) (int, error) { return b.version, b.versErr }
+
+func TestReadinessHidesErrorDetails(t *testing.T) {
+	secret := errors.New("dial tcp 10.0.0.5:5432: password authentication failed for user \"restapi\"")
+	tests := []struct {
+		name  string
+		store brokenStorage
+		check string
+		want  string
+	}{
+		{"ping", brokenStorage{pingErr: secret, version: migrate.Latest()}, "database", checkErrUnavailable},
+		{"schema version", brokenStorage{versErr: secret}, "migrations", checkErrUnavailable},
+		{"schema behind", brokenStorage{version: migrate.Latest() - 1}, "migrations", checkErrSchemaVersionMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, store := newTestService(t, hasher.Bcrypt)
+			tt.store.Storage = store
+			svc.StorageImpl = tt.store
+
+			readiness, ready := svc.Readiness(context.Background())
+			if ready || readiness.Status != StatusUnavailable {
+				t.Fatalf("readiness = %+v, want %s", readiness, StatusUnavailable)
+			}
+			got := readiness.Checks[tt.check]
+			if got.Status != StatusUnavailable || got.Error != tt.want {
+				t.Fatalf("check %s = %+v, want error %q", tt.check, got, tt.want)
+			}
+			for name, c := range readiness.Checks {
+				if strings.Contains(c.Error, "10.0.0.5") {
+					t.Fatalf("check %s leaks error details: %q", name, c.Error)
+				}
+			}
+		})
+	}
+}
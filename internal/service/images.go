@@ -0,0 +1,72 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"restapi/internal/images"
+	"restapi/internal/metrics"
+)
+
+// ErrUploadsDisabled возвращается, если хранилище изображений не настроено
+var ErrUploadsDisabled = errors.New("image uploads are disabled")
+
+// UploadedImage — адреса сохраненного изображения и его миниатюры
+type UploadedImage struct {
+	URL          string
+	ThumbnailURL string
+	ContentType  string
+	Width        int
+	Height       int
+}
+
+// UploadImage проверяет и перекодирует изображение, строит миниатюру и сохраняет оба файла в Blobs
+func (s *Service) UploadImage(ctx context.Context, userID int, r io.Reader) (UploadedImage, error) {
+	if s.Blobs == nil {
+		return UploadedImage{}, ErrUploadsDisabled
+	}
+	s.log(ctx).Infof("Uploading image for user ID: %d", userID)
+	img, err := images.Process(r, s.MaxImageSize)
+	if err != nil {
+		s.log(ctx).Warnf("Rejected image upload: %v", err)
+		return UploadedImage{}, err
+	}
+
+	name, err := randomHex(16)
+	if err != nil {
+		s.log(ctx).Errorf("Failed to generate image name: %v", err)
+		return UploadedImage{}, err
+	}
+	// первые два символа — подкаталог, чтобы не держать все файлы в одной директории
+	key := name[:2] + "/" + name[2:] + img.Ext
+	thumbKey := name[:2] + "/" + name[2:] + "_thumb" + img.Ext
+	if err := s.Blobs.Put(ctx, key, bytes.NewReader(img.Original), img.ContentType); err != nil {
+		s.log(ctx).Errorf("Failed to store image: %v", err)
+		return UploadedImage{}, err
+	}
+	if err := s.Blobs.Put(ctx, thumbKey, bytes.NewReader(img.Thumbnail), img.ContentType); err != nil {
+		s.log(ctx).Errorf("Failed to store thumbnail: %v", err)
+		s.Blobs.Delete(ctx, key)
+		return UploadedImage{}, err
+	}
+	metrics.ImagesUploadedTotal.Inc()
+	return UploadedImage{
+		URL:          s.Blobs.URL(key),
+		ThumbnailURL: s.Blobs.URL(thumbKey),
+		ContentType:  img.ContentType,
+		Width:        img.Width,
+		Height:       img.Height,
+	}, nil
+}
+
+// randomHex возвращает n случайных байт в hex: такие имена безопасны для любой файловой системы
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
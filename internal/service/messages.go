@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"restapi/internal/events"
+	"restapi/internal/metrics"
+	"restapi/internal/models"
+	"restapi/internal/storage"
+)
+
+// ErrSelfMessage возвращается при попытке написать по собственному объявлению
+var ErrSelfMessage = errors.New("cannot message yourself")
+
+// ThreadsPage — страница веток переписки пользователя
+type ThreadsPage struct {
+	Items    []models.Thread
+	Page     int
+	PageSize int
+	Total    int
+}
+
+// MessagesPage — страница сообщений ветки
+type MessagesPage struct {
+	Items    []models.Message
+	Page     int
+	PageSize int
+	Total    int
+}
+
+// StartThread пишет автору объявления: открывает ветку покупателя (или продолжает существующую) и отправляет сообщение.
+// Писать можно только по объявлению, которое покупатель видит, и не по своему
+func (s *Service) StartThread(ctx context.Context, buyerID, adID int, body string) (models.Thread, error) {
+	s.log(ctx).Infof("User ID %d starts thread for ad ID: %d", buyerID, adID)
+	ad, err := s.GetAd(ctx, adID, models.Viewer{UserID: buyerID})
+	if err != nil {
+		return models.Thread{}, err
+	}
+	if ad.UserID == buyerID {
+		return models.Thread{}, ErrSelfMessage
+	}
+	threadID, err := s.StorageImpl.GetOrCreateThread(ctx, adID, buyerID, ad.UserID)
+	if err != nil {
+		s.log(ctx).Errorf("Failed to create thread: %v", err)
+		return models.Thread{}, err
+	}
+	if _, err := s.addMessage(ctx, threadID, buyerID, body, []int{buyerID, ad.UserID}); err != nil {
+		return models.Thread{}, err
+	}
+	thread, err := s.StorageImpl.GetThread(ctx, threadID, buyerID)
+	if err != nil {
+		s.log(ctx).Errorf("Failed to get thread: %v", err)
+		return models.Thread{}, err
+	}
+	return thread, nil
+}
+
+// SendMessage отправляет сообщение в ветку, участником которой является пользователь
+func (s *Service) SendMessage(ctx context.Context, userID, threadID int, body string) (models.Message, error) {
+	s.log(ctx).Infof("User ID %d sends message to thread ID: %d", userID, threadID)
+	thread, err := s.participantThread(ctx, userID, threadID)
+	if err != nil {
+		return models.Message{}, err
+	}
+	return s.addMessage(ctx, threadID, userID, body, []int{thread.BuyerID, thread.SellerID})
+}
+
+// ListThreads возвращает ветки пользователя с последним сообщением и числом непрочитанных, свежие первыми
+func (s *Service) ListThreads(ctx context.Context, userID, page, pageSize int) (ThreadsPage, error) {
+	page, pageSize = pageBounds(page, pageSize, 20)
+	s.log(ctx).Infof("Listing threads of user ID %d, page: %d, pageSize: %d", userID, page, pageSize)
+	threads, total, err := s.StorageImpl.ListThreads(ctx, userID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		s.log(ctx).Errorf("Failed to list threads: %v", err)
+		return ThreadsPage{}, err
+	}
+	return ThreadsPage{Items: threads, Page: page, PageSize: pageSize, Total: total}, nil
+}
+
+// ThreadMessages возвращает сообщения ветки от старых к новым; чтение не отмечает их прочитанными
+func (s *Service) ThreadMessages(ctx context.Context, userID, threadID, page, pageSize int) (MessagesPage, error) {
+	page, pageSize = pageBounds(page, pageSize, 50)
+	s.log(ctx).Infof("Fetching messages of thread ID %d for user ID: %d", threadID, userID)
+	if _, err := s.participantThread(ctx, userID, threadID); err != nil {
+		return MessagesPage{}, err
+	}
+	messages, total, err := s.StorageImpl.GetMessages(ctx, threadID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		s.log(ctx).Errorf("Failed to get messages: %v", err)
+		return MessagesPage{}, err
+	}
+	return MessagesPage{Items: messages, Page: page, PageSize: pageSize, Total: total}, nil
+}
+
+// MarkThreadRead отмечает все сообщения ветки прочитанными для пользователя
+func (s *Service) MarkThreadRead(ctx context.Context, userID, threadID int) error {
+	s.log(ctx).Infof("User ID %d marks thread ID %d as read", userID, threadID)
+	if _, err := s.participantThread(ctx, userID, threadID); err != nil {
+		return err
+	}
+	if err := s.StorageImpl.MarkThreadRead(ctx, threadID, userID); err != nil {
+		s.log(ctx).Errorf("Failed to mark thread as read: %v", err)
+		return err
+	}
+	return nil
+}
+
+// addMessage сохраняет сообщение и уведомляет участников ветки (у отправителя может быть открыто несколько клиентов)
+func (s *Service) addMessage(ctx context.Context, threadID, senderID int, body string, participants []int) (models.Message, error) {
+	msg, err := s.StorageImpl.AddMessage(ctx, threadID, senderID, body)
+	if err != nil {
+		s.log(ctx).Errorf("Failed to add message: %v", err)
+		return models.Message{}, err
+	}
+	metrics.MessagesSentTotal.Inc()
+	s.publish(events.Event{Type: events.MessageCreated, Data: msg, Audience: events.Audience{UserIDs: participants}})
+	return msg, nil
+}
+
+// participantThread загружает ветку; чужая ветка неотличима от несуществующей
+func (s *Service) participantThread(ctx context.Context, userID, threadID int) (models.Thread, error) {
+	thread, err := s.StorageImpl.GetThread(ctx, threadID, userID)
+	if err != nil {
+		if !errors.Is(err, storage.ErrThreadNotFound) {
+			s.log(ctx).Errorf("Failed to get thread: %v", err)
+		}
+		return models.Thread{}, err
+	}
+	if !thread.HasParticipant(userID) {
+		s.log(ctx).Warnf("User ID %d is not a participant of thread ID %d", userID, threadID)
+		return models.Thread{}, storage.ErrThreadNotFound
+	}
+	return thread, nil
+}
+
+// pageBounds приводит номер и размер страницы к допустимым значениям
+func pageBounds(page, pageSize, defaultSize int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = defaultSize
+	}
+	return page, pageSize
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"restapi/internal/events"
+	"restapi/internal/metrics"
+	"restapi/internal/models"
+	"restapi/internal/storage"
+	"slices"
+)
+
+// SubmitAd отправляет черновик или отклоненное объявление на проверку
+func (s *Service) SubmitAd(ctx context.Context, userID, adID int) error {
+	s.log(ctx).Infof("Submitting ad ID %d for review by user ID: %d", adID, userID)
+	if _, err := s.ownedAd(ctx, userID, adID); err != nil {
+		return err
+	}
+	return s.transitionAd(ctx, adID, []string{models.AdStatusDraft, models.AdStatusRejected}, models.AdStatusPendingReview, "", 0)
+}
+
+// ArchiveAd снимает опубликованное или отклоненное объявление с показа
+func (s *Service) ArchiveAd(ctx context.Context, userID, adID int) error {
+	s.log(ctx).Infof("Archiving ad ID %d by user ID: %d", adID, userID)
+	if _, err := s.ownedAd(ctx, userID, adID); err != nil {
+		return err
+	}
+	return s.transitionAd(ctx, adID, []string{models.AdStatusPublished, models.AdStatusRejected}, models.AdStatusArchived, "", 0)
+}
+
+// ModerationQueue возвращает объявления, ожидающие проверки, начиная с самых старых
+func (s *Service) ModerationQueue(ctx context.Context, filter models.AdsFilter) (AdsPage, error) {
+	filter.Status = models.AdStatusPendingReview
+	filter.SortBy = models.SortByCreatedAt
+	filter.SortOrder = "ASC"
+	return s.GetAdsPage(ctx, filter, models.Viewer{CanModerate: true})
+}
+
+// ApproveAd публикует объявление из очереди модерации.
+// Созданное через CreateAd объявление попадает в ленту только здесь, поэтому и по сохраненным поискам оно проверяется после одобрения
+func (s *Service) ApproveAd(ctx context.Context, moderatorID, adID int) error {
+	s.log(ctx).Infof("Moderator ID %d approves ad ID: %d", moderatorID, adID)
+	if err := s.transitionAd(ctx, adID, []string{models.AdStatusPendingReview}, models.AdStatusPublished, "", moderatorID); err != nil {
+		return err
+	}
+	metrics.AdsModeratedTotal.WithLabelValues("approved").Inc()
+	s.enqueueMatch(ctx, adID)
+	// цену могли снизить, пока объявление было на проверке: уведомления о снижении отправляются при публикации
+	ad, err := s.StorageImpl.GetAd(ctx, adID)
+	if err != nil {
+		s.log(ctx).Errorf("Failed to load approved ad for price drop notifications: %v", err)
+		return nil
+	}
+	s.notifyPriceDrop(ctx, ad)
+	return nil
+}
+
+// RejectAd отклоняет объявление из очереди модерации с указанием причины
+func (s *Service) RejectAd(ctx context.Context, moderatorID, adID int, reason string) error {
+	s.log(ctx).Infof("Moderator ID %d rejects ad ID: %d", moderatorID, adID)
+	if err := s.transitionAd(ctx, adID, []string{models.AdStatusPendingReview}, models.AdStatusRejected, reason, moderatorID); err != nil {
+		return err
+	}
+	metrics.AdsModeratedTotal.WithLabelValues("rejected").Inc()
+	return nil
+}
+
+func (s *Service) transitionAd(ctx context.Context, adID int, from []string, to, reason string, moderatorID int) error {
+	err := s.StorageImpl.TransitionAd(ctx, adID, from, to, reason, moderatorID)
+	if err != nil {
+		if !errors.Is(err, storage.ErrAdNotFound) && !errors.Is(err, storage.ErrAdStatusConflict) {
+			s.log(ctx).Errorf("Failed to change ad status: %v", err)
+		}
+		return err
+	}
+	s.publishAdByID(ctx, events.AdUpdated, adID, slices.Contains(from, models.AdStatusPublished))
+	return nil
+}
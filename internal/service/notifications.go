@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"restapi/internal/events"
+	"restapi/internal/metrics"
+	"restapi/internal/models"
+	"restapi/internal/storage"
+)
+
+// NotificationsPage — страница входящих уведомлений и число непрочитанных
+type NotificationsPage struct {
+	Items    []models.Notification
+	Page     int
+	PageSize int
+	Total    int
+	Unread   int
+}
+
+// Notifications возвращает уведомления пользователя, новые первыми; unreadOnly оставляет только непрочитанные
+func (s *Service) Notifications(ctx context.Context, userID int, unreadOnly bool, page, pageSize int) (NotificationsPage, error) {
+	page, pageSize = pageBounds(page, pageSize, 20)
+	s.log(ctx).Infof("Listing notifications of user ID %d, page: %d, pageSize: %d", userID, page, pageSize)
+	items, total, err := s.StorageImpl.ListNotifications(ctx, userID, unreadOnly, pageSize, (page-1)*pageSize)
+	if err != nil {
+		s.log(ctx).Errorf("Failed to list notifications: %v", err)
+		return NotificationsPage{}, err
+	}
+	unread, err := s.StorageImpl.CountUnreadNotifications(ctx, userID)
+	if err != nil {
+		s.log(ctx).Errorf("Failed to count unread notifications: %v", err)
+		return NotificationsPage{}, err
+	}
+	return NotificationsPage{Items: items, Page: page, PageSize: pageSize, Total: total, Unread: unread}, nil
+}
+
+// MarkNotificationRead отмечает уведомление пользователя прочитанным
+func (s *Service) MarkNotificationRead(ctx context.Context, userID, notificationID int) error {
+	s.log(ctx).Infof("User ID %d marks notification ID %d as read", userID, notificationID)
+	err := s.StorageImpl.MarkNotificationRead(ctx, userID, notificationID)
+	if err != nil && !errors.Is(err, storage.ErrNotificationNotFound) {
+		s.log(ctx).Errorf("Failed to mark notification as read: %v", err)
+	}
+	return err
+}
+
+// MarkAllNotificationsRead отмечает прочитанными все уведомления пользователя
+func (s *Service) MarkAllNotificationsRead(ctx context.Context, userID int) error {
+	s.log(ctx).Infof("User ID %d marks all notifications as read", userID)
+	if err := s.StorageImpl.MarkAllNotificationsRead(ctx, userID); err != nil {
+		s.log(ctx).Errorf("Failed to mark notifications as read: %v", err)
+		return err
+	}
+	return nil
+}
+
+// notify сохраняет уведомление и отправляет его получателю в поток событий.
+// Повтор уже отправленного совпадения с сохраненным поиском молча пропускается
+func (s *Service) notify(ctx context.Context, n models.Notification) error {
+	n, created, err := s.StorageImpl.AddNotification(ctx, n)
+	if err != nil {
+		s.log(ctx).Errorf("Failed to add notification: %v", err)
+		return err
+	}
+	if !created {
+		return nil
+	}
+	metrics.NotificationsCreatedTotal.WithLabelValues(n.Type).Inc()
+	s.publish(events.Event{Type: events.NotificationCreated, Data: n, Audience: events.Audience{UserIDs: []int{n.UserID}}})
+	return nil
+}
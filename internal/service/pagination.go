@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"restapi/internal/models"
+)
+
+// AdsPage — страница ленты с общим числом подходящих объявлений.
+// При пагинации курсором Page не заполняется, а соседние страницы задаются NextCursor/PrevCursor
+type AdsPage struct {
+	Items      []models.Ad
+	Page       int
+	PageSize   int
+	Total      int
+	NextCursor string
+	PrevCursor string
+}
+
+// TotalPages возвращает число страниц при текущем размере страницы
+func (p AdsPage) TotalPages() int {
+	if p.PageSize <= 0 {
+		return 0
+	}
+	return (p.Total + p.PageSize - 1) / p.PageSize
+}
+
+// GetAdsPage возвращает страницу ленты по номеру вместе с общим числом объявлений
+func (s *Service) GetAdsPage(ctx context.Context, filter models.AdsFilter, viewer models.Viewer) (AdsPage, error) {
+	filter, err := s.withCategoryFilter(ctx, filter.Normalize())
+	if err != nil {
+		return AdsPage{}, err
+	}
+	ads, err := s.GetAds(ctx, filter, viewer)
+	if err != nil {
+		return AdsPage{}, err
+	}
+	total, err := s.countAds(ctx, filter, viewer)
+	if err != nil {
+		return AdsPage{}, err
+	}
+	return AdsPage{Items: ads, Page: filter.Page, PageSize: filter.PageSize, Total: total}, nil
+}
+
+func (s *Service) countAds(ctx context.Context, filter models.AdsFilter, viewer models.Viewer) (int, error) {
+	total, err := s.StorageImpl.CountAds(ctx, filter, viewer)
+	if err != nil {
+		s.log(ctx).Errorf("Failed to count ads: %v", err)
+		return 0, err
+	}
+	return total, nil
+}
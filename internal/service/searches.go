@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"restapi/internal/models"
+	"restapi/internal/storage"
+	"sync"
+	"time"
+)
+
+// MaxSavedSearches — максимальное число сохраненных поисков у пользователя
+const MaxSavedSearches = 20
+
+// matchTimeout ограничивает проверку одного объявления по сохраненным поискам
+const matchTimeout = 10 * time.Second
+
+// ErrTooManySavedSearches возвращается при попытке сохранить поиск сверх MaxSavedSearches
+var ErrTooManySavedSearches = errors.New("too many saved searches")
+
+// matcher — очередь объявлений для фоновой проверки по сохраненным поискам
+type matcher struct {
+	mu     sync.Mutex
+	queue  chan int
+	closed bool
+	done   chan struct{}
+}
+
+// CreateSavedSearch сохраняет поиск пользователя
+func (s *Service) CreateSavedSearch(ctx context.Context, search models.SavedSearch) (models.SavedSearch, error) {
+	s.log(ctx).Infof("Saving search %q for user ID: %d", search.Name, search.UserID)
+	if err := s.checkCategory(ctx, search.Category); err != nil {
+		return models.SavedSearch{}, err
+	}
+	filter := search.Filter()
+	search.SortBy, search.SortOrder = filter.SortBy, filter.SortOrder
+	// лимит проверяется хранилищем в одной транзакции со вставкой, чтобы параллельные запросы его не превысили
+	search, err := s.StorageImpl.CreateSavedSearch(ctx, search, MaxSavedSearches)
+	switch {
+	case errors.Is(err, storage.ErrSavedSearchLimit):
+		return models.SavedSearch{}, ErrTooManySavedSearches
+	case err != nil:
+		s.log(ctx).Errorf("Failed to save search: %v", err)
+		return models.SavedSearch{}, err
+	}
+	return search, nil
+}
+
+// SavedSearches возвращает сохраненные поиски пользователя
+func (s *Service) SavedSearches(ctx context.Context, userID int) ([]models.SavedSearch, error) {
+	searches, err := s.StorageImpl.ListSavedSearches(ctx, userID)
+	if err != nil {
+		s.log(ctx).Errorf("Failed to list saved searches: %v", err)
+		return nil, err
+	}
+	return searches, nil
+}
+
+// DeleteSavedSearch удаляет сохраненный поиск пользователя; уже полученные уведомления остаются
+func (s *Service) DeleteSavedSearch(ctx context.Context, userID, searchID int) error {
+	s.log(ctx).Infof("Deleting saved search ID %d of user ID: %d", searchID, userID)
+	err := s.StorageImpl.DeleteSavedSearch(ctx, userID, searchID)
+	if err != nil && !errors.Is(err, storage.ErrSavedSearchNotFound) {
+		s.log(ctx).Errorf("Failed to delete saved search: %v", err)
+	}
+	return err
+}
+
+// StartMatcher запускает фоновую проверку новых объявлений по сохраненным поискам.
+// Без него объявления не проверяются; Shutdown дожидается обработки очереди
+func (s *Service) StartMatcher(queueSize int) {
+	m := &matcher{queue: make(chan int, queueSize), done: make(chan struct{})}
+	s.matcher = m
+	go func() {
+		defer close(m.done)
+		for adID := range m.queue {
+			ctx, cancel := context.WithTimeout(context.Background(), matchTimeout)
+			s.matchSavedSearches(ctx, adID)
+			cancel()
+		}
+	}()
+}
+
+// enqueueMatch ставит объявление в очередь проверки; запрос не ждет проверки, при переполненной очереди объявление пропускается
+func (s *Service) enqueueMatch(ctx context.Context, adID int) {
+	m := s.matcher
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return
+	}
+	select {
+	case m.queue <- adID:
+	default:
+		s.log(ctx).Warnf("Saved search queue is full, skipping ad ID: %d", adID)
+	}
+}
+
+// stopMatcher закрывает очередь и ждет, пока уже поставленные объявления будут проверены, но не дольше ctx
+func (s *Service) stopMatcher(ctx context.Context) {
+	m := s.matcher
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	if !m.closed {
+		m.closed = true
+		close(m.queue)
+	}
+	m.mu.Unlock()
+	select {
+	case <-m.done:
+	case <-ctx.Done():
+		s.logger.Warnf("Saved search queue was not drained before shutdown")
+	}
+}
+
+// matchSavedSearches проверяет опубликованное объявление по сохраненным поискам других пользователей
+// с теми же правилами, что и лента, и создает уведомления о совпадениях
+func (s *Service) matchSavedSearches(ctx context.Context, adID int) {
+	ad, err := s.StorageImpl.GetAd(ctx, adID)
+	if err != nil {
+		s.log(ctx).Warnf("Failed to load ad ID %d for saved searches: %v", adID, err)
+		return
+	}
+	if ad.Status != models.AdStatusPublished {
+		return
+	}
+	searches, err := s.StorageImpl.SavedSearchesForPrice(ctx, ad.Price)
+	if err != nil {
+		s.log(ctx).Errorf("Failed to find saved searches: %v", err)
+		return
+	}
+	matched := 0
+	for _, search := range searches {
+		if search.UserID == ad.UserID {
+			continue
+		}
+		filter, err := s.withCategoryFilter(ctx, search.Filter())
+		if err != nil {
+			s.log(ctx).Warnf("Skipping saved search ID %d: %v", search.ID, err)
+			continue
+		}
+		filter.AdID = adID
+		n, err := s.StorageImpl.CountAds(ctx, filter, models.Viewer{})
+		if err != nil {
+			s.log(ctx).Errorf("Failed to match saved search ID %d: %v", search.ID, err)
+			continue
+		}
+		if n == 0 {
+			continue
+		}
+		notification := models.Notification{
+			UserID:        search.UserID,
+			Type:          models.NotificationSavedSearchMatch,
+			AdID:          adID,
+			SavedSearchID: search.ID,
+		}
+		if err := s.notify(ctx, notification); err != nil {
+			continue
+		}
+		matched++
+	}
+	s.log(ctx).Infof("Ad ID %d matched %d of %d saved searches", adID, matched, len(searches))
+}
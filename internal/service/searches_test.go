@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"restapi/internal/hasher"
+	"restapi/internal/models"
+	"sync"
+	"testing"
+)
+
+func TestMatcherNotifiesMatchingSearches(t *testing.T) {
+	ctx := context.Background()
+	svc, store := newTestService(t, hasher.Bcrypt)
+	sellerID, _ := store.RegisterUser(ctx, "seller", "hash")
+	buyerID, _ := store.RegisterUser(ctx, "buyer", "hash")
+	moderatorID, _ := store.RegisterUser(ctx, "moderator", "hash")
+	// категория 11 «Велосипеды» вложена в 2 «Транспорт»
+	adID, err := store.CreateAd(ctx, sellerID, "Mountain bike", "Bike in good condition", 11,
+		[]string{"https://example.com/bike.jpg"}, 300, models.AdStatusPendingReview)
+	if err != nil {
+		t.Fatalf("CreateAd: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		search models.SavedSearch
+		want   bool
+	}{
+		{"everything", models.SavedSearch{UserID: buyerID, MaxPrice: 1000000}, true},
+		{"query matches", models.SavedSearch{UserID: buyerID, Query: "bike", MaxPrice: 1000000}, true},
+		{"query does not match", models.SavedSearch{UserID: buyerID, Query: "laptop", MaxPrice: 1000000}, false},
+		{"excluded word", models.SavedSearch{UserID: buyerID, Query: "bike -mountain", MaxPrice: 1000000}, false},
+		{"price in range", models.SavedSearch{UserID: buyerID, MinPrice: 100, MaxPrice: 300}, true},
+		{"price out of range", models.SavedSearch{UserID: buyerID, MinPrice: 400, MaxPrice: 500}, false},
+		{"exact category", models.SavedSearch{UserID: buyerID, Category: 11, MaxPrice: 1000000}, true},
+		{"parent category", models.SavedSearch{UserID: buyerID, Category: 2, MaxPrice: 1000000}, true},
+		{"other category", models.SavedSearch{UserID: buyerID, Category: 1, MaxPrice: 1000000}, false},
+		{"own ad", models.SavedSearch{UserID: sellerID, MaxPrice: 1000000}, false},
+	}
+	searchIDs := make([]int, len(tests))
+	for i, tt := range tests {
+		tt.search.Name = tt.name
+		search, err := svc.CreateSavedSearch(ctx, tt.search)
+		if err != nil {
+			t.Fatalf("CreateSavedSearch(%s): %v", tt.name, err)
+		}
+		searchIDs[i] = search.ID
+	}
+
+	svc.StartMatcher(8)
+	if err := svc.ApproveAd(ctx, moderatorID, adID); err != nil {
+		t.Fatalf("ApproveAd: %v", err)
+	}
+	// Shutdown дожидается, пока очередь будет разобрана
+	if err := svc.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	// повторная проверка того же объявления не дублирует уведомления
+	svc.matchSavedSearches(ctx, adID)
+
+	notified := map[int]int{}
+	for _, userID := range []int{buyerID, sellerID} {
+		page, err := svc.Notifications(ctx, userID, false, 1, 100)
+		if err != nil {
+			t.Fatalf("Notifications: %v", err)
+		}
+		for _, n := range page.Items {
+			if n.Type != models.NotificationSavedSearchMatch || n.AdID != adID {
+				t.Fatalf("unexpected notification %+v", n)
+			}
+			notified[n.SavedSearchID]++
+		}
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := 0
+			if tt.want {
+				want = 1
+			}
+			if got := notified[searchIDs[i]]; got != want {
+				t.Fatalf("notifications = %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestMatcherSkipsUnpublishedAds(t *testing.T) {
+	ctx := context.Background()
+	svc, store := newTestService(t, hasher.Bcrypt)
+	sellerID, _ := store.RegisterUser(ctx, "seller", "hash")
+	buyerID, _ := store.RegisterUser(ctx, "buyer", "hash")
+	if _, err := svc.CreateSavedSearch(ctx, models.SavedSearch{UserID: buyerID, Name: "all", MaxPrice: 1000000}); err != nil {
+		t.Fatalf("CreateSavedSearch: %v", err)
+	}
+
+	for _, status := range []string{models.AdStatusDraft, models.AdStatusPendingReview, models.AdStatusRejected, models.AdStatusArchived} {
+		t.Run(status, func(t *testing.T) {
+			adID, err := store.CreateAd(ctx, sellerID, "Mountain bike", "Bike in good condition", 0,
+				[]string{"https://example.com/bike.jpg"}, 300, status)
+			if err != nil {
+				t.Fatalf("CreateAd: %v", err)
+			}
+			svc.matchSavedSearches(ctx, adID)
+			page, err := svc.Notifications(ctx, buyerID, false, 1, 100)
+			if err != nil {
+				t.Fatalf("Notifications: %v", err)
+			}
+			if page.Total != 0 {
+				t.Fatalf("notifications = %+v, want none", page.Items)
+			}
+		})
+	}
+}
+
+func TestEnqueueMatchAfterShutdown(t *testing.T) {
+	svc, _ := newTestService(t, hasher.Bcrypt)
+	svc.StartMatcher(1)
+	if err := svc.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	// закрытая очередь не должна паниковать при записи
+	svc.enqueueMatch(context.Background(), 1)
+}
+
+func TestSavedSearchLimitUnderConcurrency(t *testing.T) {
+	ctx := context.Background()
+	svc, store := newTestService(t, hasher.Bcrypt)
+	buyerID, _ := store.RegisterUser(ctx, "buyer", "hash")
+
+	const attempts = 3 * MaxSavedSearches
+	var wg sync.WaitGroup
+	errs := make(chan error, attempts)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := svc.CreateSavedSearch(ctx, models.SavedSearch{UserID: buyerID, Name: fmt.Sprintf("search %d", i), MaxPrice: 1000})
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	saved := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			saved++
+		case !errors.Is(err, ErrTooManySavedSearches):
+			t.Fatalf("CreateSavedSearch: %v", err)
+		}
+	}
+	if saved != MaxSavedSearches {
+		t.Fatalf("saved %d searches, want %d", saved, MaxSavedSearches)
+	}
+	searches, err := svc.SavedSearches(ctx, buyerID)
+	if err != nil {
+		t.Fatalf("SavedSearches: %v", err)
+	}
+	if len(searches) != MaxSavedSearches {
+		t.Fatalf("stored %d searches, want %d", len(searches), MaxSavedSearches)
+	}
+}
@@ -2,37 +2,89 @@ package service
 
 import (
 	"context"
+	"crypto/subtle"
+	"errors"
 	"net/http"
 	"os"
+	"restapi/internal/blobstore"
+	"restapi/internal/events"
+	"restapi/internal/hasher"
+	"restapi/internal/logger"
+	"restapi/internal/metrics"
 	"restapi/internal/models"
 	"restapi/internal/storage"
+	"restapi/internal/user"
+	"slices"
+	"sync/atomic"
 	"time"
 
-	"github.com/golang-jwt/jwt"
 	"go.uber.org/zap"
 )
 
+var (
+	// ErrInvalidCredentials возвращается при неверной паре логин/пароль
+	ErrInvalidCredentials = errors.New("invalid login or password")
+	// ErrForbidden возвращается, если пользователь не владеет объявлением
+	ErrForbidden = errors.New("forbidden")
+	// ErrInvalidToken возвращается для неизвестного, отозванного или просроченного refresh-токена
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrUserBanned возвращается при входе или обновлении токенов заблокированным пользователем
+	ErrUserBanned = errors.New("user is banned")
+)
+
 type Service struct {
 	Port        string
 	Host        string
 	logger      *zap.SugaredLogger
 	StorageImpl storage.Storage
+	// AccessTokenTTL и RefreshTokenTTL задают время жизни выдаваемых токенов
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	// Blobs хранит загруженные изображения; nil отключает загрузку
+	Blobs blobstore.Store
+	// MaxImageSize — максимальный размер загружаемого изображения в байтах
+	MaxImageSize int64
+	// Events получает события об объявлениях и сообщениях для SSE; nil отключает поток событий
+	Events *events.Hub
+	// matcher проверяет новые объявления по сохраненным поискам; запускается StartMatcher
+	matcher *matcher
+	hasher  hasher.Hasher
+	server  *http.Server
+	// dummyHash проверяется для несуществующих логинов, чтобы время ответа не выдавало их отсутствие
+	dummyHash string
+	startedAt time.Time
+	draining  atomic.Bool
 }
 
-func NewService(port, host string, logger *zap.SugaredLogger, storage storage.Storage) *Service {
+func NewService(port, host string, logger *zap.SugaredLogger, storage storage.Storage, passwordHasher hasher.Hasher) *Service {
+	dummyHash, err := passwordHasher.Hash("dummy-password")
+	if err != nil {
+		logger.Warnf("Failed to prepare dummy password hash: %v", err)
+	}
 	return &Service{
-		Port:        port,
-		Host:        host,
-		logger:      logger,
-		StorageImpl: storage,
+		Port:            port,
+		Host:            host,
+		logger:          logger,
+		StorageImpl:     storage,
+		AccessTokenTTL:  15 * time.Minute,
+		RefreshTokenTTL: 30 * 24 * time.Hour,
+		MaxImageSize:    5 << 20,
+		hasher:          passwordHasher,
+		dummyHash:       dummyHash,
+		startedAt:       time.Now(),
 	}
 }
 
 var JWTKey = string(os.Getenv("JWT_KEY"))
 
+// log возвращает логгер текущего запроса (с request_id, user_id, route) или общий логгер сервиса
+func (s *Service) log(ctx context.Context) *zap.SugaredLogger {
+	return logger.FromContext(ctx, s.logger)
+}
+
 // ListenAndServe запускает HTTP-сервер
 func (s *Service) ListenAndServe(handler http.Handler) error {
-	httpServer := &http.Server{
+	s.server = &http.Server{
 		Addr:         s.Host + s.Port,
 		Handler:      handler,
 		ReadTimeout:  10 * time.Second,
@@ -40,69 +92,272 @@ func (s *Service) ListenAndServe(handler http.Handler) error {
 		IdleTimeout:  30 * time.Second,
 	}
 	s.logger.Infof("Starting server at %s%s", s.Host, s.Port)
-	return httpServer.ListenAndServe()
+	err := s.server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// StartDraining помечает сервис как завершающийся: /readyz начинает отвечать 503
+func (s *Service) StartDraining() {
+	s.draining.Store(true)
+}
+
+// Shutdown перестает принимать новые соединения и ждет завершения текущих запросов до истечения ctx
+func (s *Service) Shutdown(ctx context.Context) error {
+	// очередь сохраненных поисков пополняют запросы, поэтому она останавливается после них
+	defer s.stopMatcher(ctx)
+	if s.server == nil {
+		return nil
+	}
+	s.StartDraining()
+	// потоки событий не завершаются сами, поэтому закрываем их до ожидания остальных запросов
+	if s.Events != nil {
+		s.Events.Close()
+	}
+	s.logger.Infof("Shutting down server, draining in-flight requests")
+	return s.server.Shutdown(ctx)
 }
 
 // RegisterUser регистрирует нового пользователя
 func (s *Service) RegisterUser(ctx context.Context, login, password string) (int, error) {
-	s.logger.Infof("Registering user with login: %s", login)
-	id, err := s.StorageImpl.RegisterUser(ctx, login, password)
+	s.log(ctx).Infof("Registering user with login: %s", login)
+	passwordHash, err := s.hasher.Hash(password)
+	if errors.Is(err, hasher.ErrPasswordTooLong) {
+		return 0, err
+	}
 	if err != nil {
-		s.logger.Errorf("Failed to register user: %v", err)
+		s.log(ctx).Errorf("Failed to hash password: %v", err)
 		return 0, err
 	}
+	id, err := s.StorageImpl.RegisterUser(ctx, login, passwordHash)
+	if err != nil {
+		s.log(ctx).Errorf("Failed to register user: %v", err)
+		return 0, err
+	}
+	metrics.UserRegistrationsTotal.Inc()
 	return id, nil
 }
 
-// LoginUser аутентифицирует пользователя и возвращает JWT-токен
-func (s *Service) LoginUser(ctx context.Context, login, password string) (string, error) {
-	s.logger.Infof("Authenticating user with login: %s", login)
-	userID, err := s.StorageImpl.CheckUser(ctx, login, password)
+// LoginUser аутентифицирует пользователя и возвращает пару access/refresh токенов
+func (s *Service) LoginUser(ctx context.Context, login, password string) (Tokens, error) {
+	s.log(ctx).Infof("Authenticating user with login: %s", login)
+	u, err := s.authenticate(ctx, login, password)
 	if err != nil {
-		s.logger.Errorf("Failed to check user: %v", err)
-		return "", err
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": userID,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
-	})
-	s.logger.Debugf("Generated token claims: %v", token.Claims)
-	tokenString, err := token.SignedString([]byte(JWTKey))
+		metrics.LoginsTotal.WithLabelValues("failure").Inc()
+		s.log(ctx).Errorf("Failed to check user: %v", err)
+		return Tokens{}, err
+	}
+	metrics.LoginsTotal.WithLabelValues("success").Inc()
+	familyID, err := randomToken(16)
 	if err != nil {
-		s.logger.Errorf("Failed to generate token: %v", err)
-		return "", err
+		s.log(ctx).Errorf("Failed to generate token family: %v", err)
+		return Tokens{}, err
 	}
-	return tokenString, nil
+	return s.issueTokens(ctx, u, familyID)
 }
 
-// CreateAd создает новое объявление
-func (s *Service) CreateAd(ctx context.Context, userID int, title, description, imageURL string, price float64) (int, error) {
-	s.logger.Infof("Creating ad for user ID: %d", userID)
-	adID, err := s.StorageImpl.CreateAd(ctx, userID, title, description, imageURL, price)
+// authenticate проверяет пароль по сохраненному хешу и при необходимости перехеширует его
+func (s *Service) authenticate(ctx context.Context, login, password string) (user.User, error) {
+	u, err := s.StorageImpl.GetUserByLogin(ctx, login)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		s.hasher.Verify(s.dummyHash, password)
+		return user.User{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return user.User{}, err
+	}
+
+	ok, err := s.hasher.Verify(u.Password, password)
+	if errors.Is(err, hasher.ErrUnknownFormat) {
+		// старые записи хранят пароль в открытом виде
+		ok, err = subtle.ConstantTimeCompare([]byte(u.Password), []byte(password)) == 1, nil
+	}
+	if err != nil {
+		return user.User{}, err
+	}
+	if !ok {
+		return user.User{}, ErrInvalidCredentials
+	}
+	// блокировка проверяется только после пароля, чтобы не раскрывать ее по неверному паролю
+	if u.Banned() {
+		return user.User{}, ErrUserBanned
+	}
+
+	if s.hasher.NeedsRehash(u.Password) {
+		s.rehashPassword(ctx, u.ID, password)
+	}
+	return u, nil
+}
+
+// rehashPassword сохраняет хеш текущим алгоритмом; ошибка не мешает входу
+func (s *Service) rehashPassword(ctx context.Context, userID int, password string) {
+	passwordHash, err := s.hasher.Hash(password)
+	if errors.Is(err, hasher.ErrPasswordTooLong) {
+		// длинный пароль остается под старым хешем: bcrypt не может его сохранить
+		s.log(ctx).Infof("Password for user ID %d is too long to rehash, keeping the old hash", userID)
+		return
+	}
+	if err != nil {
+		s.log(ctx).Errorf("Failed to rehash password for user ID %d: %v", userID, err)
+		return
+	}
+	if err := s.StorageImpl.UpdatePasswordHash(ctx, userID, passwordHash); err != nil {
+		s.log(ctx).Errorf("Failed to store rehashed password for user ID %d: %v", userID, err)
+		return
+	}
+	s.log(ctx).Infof("Password hash upgraded for user ID: %d", userID)
+}
+
+// CreateAd создает новое объявление с галереей: черновик или сразу на проверку модератору
+func (s *Service) CreateAd(ctx context.Context, userID int, title, description string, categoryID int, images []string, price float64, draft bool) (int, error) {
+	s.log(ctx).Infof("Creating ad for user ID: %d, category ID: %d, images: %d, draft: %t", userID, categoryID, len(images), draft)
+	if err := s.checkCategory(ctx, categoryID); err != nil {
+		return 0, err
+	}
+	status := models.AdStatusPendingReview
+	if draft {
+		status = models.AdStatusDraft
+	}
+	adID, err := s.StorageImpl.CreateAd(ctx, userID, title, description, categoryID, images, price, status)
 	if err != nil {
-		s.logger.Errorf("Failed to create ad: %v", err)
+		s.log(ctx).Errorf("Failed to create ad: %v", err)
 		return 0, err
 	}
+	metrics.AdsCreatedTotal.Inc()
+	s.publishAdByID(ctx, events.AdCreated, adID, false)
 	return adID, nil
 }
 
 // GetAds возвращает список объявлений
-func (s *Service) GetAds(ctx context.Context, page, pageSize int, sortBy, sortOrder string, minPrice, maxPrice float64, userID int) ([]models.Ad, error) {
-	s.logger.Infof("Fetching ads for page: %d, pageSize: %d", page, pageSize)
-	ads, err := s.StorageImpl.GetAds(ctx, page, pageSize, sortBy, sortOrder, minPrice, maxPrice)
+func (s *Service) GetAds(ctx context.Context, filter models.AdsFilter, viewer models.Viewer) ([]models.Ad, error) {
+	s.log(ctx).Infof("Fetching ads for page: %d, pageSize: %d, query: %q", filter.Page, filter.PageSize, filter.Query)
+	ads, err := s.StorageImpl.GetAds(ctx, filter, viewer)
 	if err != nil {
-		s.logger.Errorf("Failed to get ads: %v", err)
+		s.log(ctx).Errorf("Failed to get ads: %v", err)
 		return nil, err
 	}
-	if userID != 0 {
-		for i := range ads {
-			isOwner, err := s.StorageImpl.IsAdOwner(ctx, ads[i].ID, userID)
-			if err != nil {
-				s.logger.Errorf("Failed to check ad owner for ad ID %d: %v", ads[i].ID, err)
-				continue
-			}
-			ads[i].IsOwner = isOwner
+	return ads, nil
+}
+
+// GetAd возвращает объявление по ID; неопубликованное видят только автор и модераторы
+func (s *Service) GetAd(ctx context.Context, adID int, viewer models.Viewer) (models.Ad, error) {
+	s.log(ctx).Infof("Fetching ad ID: %d", adID)
+	ad, err := s.StorageImpl.GetAd(ctx, adID)
+	if err != nil {
+		if !errors.Is(err, storage.ErrAdNotFound) {
+			s.log(ctx).Errorf("Failed to get ad: %v", err)
+		}
+		return models.Ad{}, err
+	}
+	ad.IsOwner = viewer.UserID != 0 && ad.UserID == viewer.UserID
+	if ad.Status != models.AdStatusPublished && !ad.IsOwner && !viewer.CanModerate {
+		return models.Ad{}, storage.ErrAdNotFound
+	}
+	if viewer.UserID != 0 {
+		if ad.IsFavorite, err = s.StorageImpl.IsFavorite(ctx, viewer.UserID, adID); err != nil {
+			s.log(ctx).Errorf("Failed to check favorite: %v", err)
+			return models.Ad{}, err
 		}
 	}
-	return ads, nil
+	return ad, nil
+}
+
+// UpdateAd применяет изменения к объявлению, если пользователь его автор
+func (s *Service) UpdateAd(ctx context.Context, userID, adID int, update models.AdUpdate) (models.Ad, error) {
+	s.log(ctx).Infof("Updating ad ID %d by user ID: %d", adID, userID)
+	ad, err := s.ownedAd(ctx, userID, adID)
+	if err != nil {
+		return models.Ad{}, err
+	}
+	if update.CategoryID != nil {
+		if err := s.checkCategory(ctx, *update.CategoryID); err != nil {
+			return models.Ad{}, err
+		}
+	}
+	current := ad
+	if update.Title != nil {
+		ad.Title = *update.Title
+	}
+	if update.Description != nil {
+		ad.Description = *update.Description
+	}
+	gallery := imageURLs(ad.Images)
+	switch {
+	case update.Images != nil:
+		gallery = *update.Images
+	case update.ImageURL != nil && len(gallery) > 0:
+		gallery[0] = *update.ImageURL
+	case update.ImageURL != nil:
+		gallery = []string{*update.ImageURL}
+	}
+	galleryChanged := !slices.Equal(gallery, imageURLs(ad.Images))
+	if len(gallery) > 0 {
+		ad.ImageURL = gallery[0]
+	}
+	if update.Price != nil {
+		ad.Price = *update.Price
+	}
+	if update.CategoryID != nil {
+		ad.CategoryID = *update.CategoryID
+	}
+	// измененный текст, фото или категория проходят модерацию заново; смена только цены статус не меняет.
+	// PUT передает все поля, поэтому сравниваются значения, а не наличие полей.
+	// Сам статус выбирает хранилище под блокировкой, чтобы не затереть решение модератора
+	contentChanged := ad.Title != current.Title || ad.Description != current.Description || ad.CategoryID != current.CategoryID || galleryChanged
+	// галерея пересоздается только при изменении, чтобы ID изображений оставались стабильными
+	var newGallery []string
+	if galleryChanged {
+		newGallery = gallery
+	}
+	res, err := s.StorageImpl.UpdateAd(ctx, ad, newGallery, contentChanged)
+	if err != nil {
+		if !errors.Is(err, storage.ErrAdNotFound) && !errors.Is(err, storage.ErrAdStatusConflict) {
+			s.log(ctx).Errorf("Failed to update ad: %v", err)
+		}
+		return models.Ad{}, err
+	}
+	ad.Status, ad.RejectionReason = res.Status, res.RejectionReason
+	if galleryChanged {
+		ad.Images = res.Images
+	}
+	s.publishAd(events.AdUpdated, ad, res.PrevStatus == models.AdStatusPublished)
+	// о снижении цены узнают, только если объявление осталось в ленте; иначе — после одобрения модератором
+	if ad.Price < res.OldPrice && ad.Status == models.AdStatusPublished {
+		s.notifyPriceDrop(ctx, ad)
+	}
+	ad.IsOwner = true
+	return ad, nil
+}
+
+// DeleteAd удаляет объявление, если пользователь его автор
+func (s *Service) DeleteAd(ctx context.Context, userID, adID int) error {
+	s.log(ctx).Infof("Deleting ad ID %d by user ID: %d", adID, userID)
+	ad, err := s.ownedAd(ctx, userID, adID)
+	if err != nil {
+		return err
+	}
+	if err := s.StorageImpl.DeleteAd(ctx, adID); err != nil {
+		s.log(ctx).Errorf("Failed to delete ad: %v", err)
+		return err
+	}
+	s.publishAdDeleted(ad)
+	return nil
+}
+
+// ownedAd загружает объявление и проверяет, что userID его автор
+func (s *Service) ownedAd(ctx context.Context, userID, adID int) (models.Ad, error) {
+	ad, err := s.StorageImpl.GetAd(ctx, adID)
+	if err != nil {
+		if !errors.Is(err, storage.ErrAdNotFound) {
+			s.log(ctx).Errorf("Failed to get ad: %v", err)
+		}
+		return models.Ad{}, err
+	}
+	if ad.UserID != userID {
+		s.log(ctx).Warnf("User ID %d is not the owner of ad ID %d", userID, adID)
+		return models.Ad{}, ErrForbidden
+	}
+	return ad, nil
 }
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"restapi/internal/hasher"
+	"restapi/internal/models"
+	"restapi/internal/storage"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// newTestService создает сервис поверх хранилища в памяти с указанным алгоритмом паролей
+func newTestService(t *testing.T, algorithm string) (*Service, *storage.StorageMemory) {
+	t.Helper()
+	JWTKey = "test-key"
+	passwordHasher, err := hasher.New(algorithm)
+	if err != nil {
+		t.Fatalf("hasher.New: %v", err)
+	}
+	store := storage.NewMemory()
+	return NewService(":0", "", zap.NewNop().Sugar(), store, passwordHasher), store
+}
+
+// storedHash возвращает хеш пароля, сохраненный для логина
+func storedHash(t *testing.T, store *storage.StorageMemory, login string) string {
+	t.Helper()
+	u, err := store.GetUserByLogin(context.Background(), login)
+	if err != nil {
+		t.Fatalf("GetUserByLogin: %v", err)
+	}
+	return u.Password
+}
+
+func TestLoginRehashesPassword(t *testing.T) {
+	ctx := context.Background()
+	argon, _ := hasher.New(hasher.Argon2id)
+	argonHash, _ := argon.Hash("secret1")
+
+	tests := []struct {
+		name      string
+		algorithm string
+		stored    string
+		password  string
+		prefix    string
+	}{
+		{"plain text to argon2id", hasher.Argon2id, "secret1", "secret1", "$argon2id$"},
+		{"argon2id to bcrypt", hasher.Bcrypt, argonHash, "secret1", "$2a$"},
+		{"too long for bcrypt keeps argon2id", hasher.Bcrypt, "", strings.Repeat("a", 100), "$argon2id$"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, store := newTestService(t, tt.algorithm)
+			stored := tt.stored
+			if stored == "" {
+				stored, _ = argon.Hash(tt.password)
+			}
+			if _, err := store.RegisterUser(ctx, "alice", stored); err != nil {
+				t.Fatalf("RegisterUser: %v", err)
+			}
+			if _, err := svc.LoginUser(ctx, "alice", tt.password); err != nil {
+				t.Fatalf("LoginUser: %v", err)
+			}
+			if got := storedHash(t, store, "alice"); !strings.HasPrefix(got, tt.prefix) {
+				t.Fatalf("stored hash = %q, want prefix %q", got, tt.prefix)
+			}
+			// после перехеширования вход по тому же паролю продолжает работать, а по чужому — нет
+			if _, err := svc.LoginUser(ctx, "alice", tt.password); err != nil {
+				t.Fatalf("second LoginUser: %v", err)
+			}
+			if _, err := svc.LoginUser(ctx, "alice", "wrong-password"); !errors.Is(err, ErrInvalidCredentials) {
+				t.Fatalf("LoginUser with wrong password = %v, want ErrInvalidCredentials", err)
+			}
+		})
+	}
+}
+
+func TestRegisterUserPasswordTooLong(t *testing.T) {
+	svc, _ := newTestService(t, hasher.Bcrypt)
+	_, err := svc.RegisterUser(context.Background(), "alice", strings.Repeat("a", hasher.BcryptMaxPasswordBytes+1))
+	if !errors.Is(err, hasher.ErrPasswordTooLong) {
+		t.Fatalf("RegisterUser = %v, want ErrPasswordTooLong", err)
+	}
+}
+
+// raceStorage выполняет before перед сохранением правки: так моделируется решение модератора,
+// принятое между чтением объявления сервисом и записью
+type raceStorage struct {
+	storage.Storage
+	before func(ctx context.Context, adID int) error
+}
+
+func (r raceStorage) UpdateAd(ctx context.Context, ad models.Ad, images []string, contentChanged bool) (models.AdUpdateResult, error) {
+	if err := r.before(ctx, ad.ID); err != nil {
+		return models.AdUpdateResult{}, err
+	}
+	return r.Storage.UpdateAd(ctx, ad, images, contentChanged)
+}
+
+func TestUpdateAdKeepsConcurrentModeration(t *testing.T) {
+	pending := []string{models.AdStatusPendingReview}
+	tests := []struct {
+		name     string
+		decision func(ctx context.Context, store *storage.StorageMemory, adID int) error
+		update   models.AdUpdate
+		want     string
+		err      error
+	}{
+		{"approved during price edit", func(ctx context.Context, store *storage.StorageMemory, adID int) error {
+			return store.TransitionAd(ctx, adID, pending, models.AdStatusPublished, "", 1)
+		}, models.AdUpdate{Price: ptr(250.0)}, models.AdStatusPublished, nil},
+		{"rejected during price edit", func(ctx context.Context, store *storage.StorageMemory, adID int) error {
+			return store.TransitionAd(ctx, adID, pending, models.AdStatusRejected, "blurry photo", 1)
+		}, models.AdUpdate{Price: ptr(250.0)}, models.AdStatusRejected, nil},
+		{"approved during title edit", func(ctx context.Context, store *storage.StorageMemory, adID int) error {
+			return store.TransitionAd(ctx, adID, pending, models.AdStatusPublished, "", 1)
+		}, models.AdUpdate{Title: ptr("Road bike")}, models.AdStatusPendingReview, nil},
+		{"archived during edit", func(ctx context.Context, store *storage.StorageMemory, adID int) error {
+			return store.TransitionAd(ctx, adID, pending, models.AdStatusArchived, "", 0)
+		}, models.AdUpdate{Price: ptr(250.0)}, models.AdStatusArchived, storage.ErrAdStatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			svc, store := newTestService(t, hasher.Bcrypt)
+			sellerID, _ := store.RegisterUser(ctx, "seller", "hash")
+			adID, err := store.CreateAd(ctx, sellerID, "Mountain bike", "Bike in good condition", 0,
+				[]string{"https://example.com/bike.jpg"}, 300, models.AdStatusPendingReview)
+			if err != nil {
+				t.Fatalf("CreateAd: %v", err)
+			}
+			svc.StorageImpl = raceStorage{Storage: store, before: func(ctx context.Context, adID int) error {
+				return tt.decision(ctx, store, adID)
+			}}
+
+			ad, err := svc.UpdateAd(ctx, sellerID, adID, tt.update)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("UpdateAd = %v, want %v", err, tt.err)
+			}
+			if err == nil && ad.Status != tt.want {
+				t.Fatalf("returned status = %s, want %s", ad.Status, tt.want)
+			}
+			stored, err := store.GetAd(ctx, adID)
+			if err != nil {
+				t.Fatalf("GetAd: %v", err)
+			}
+			if stored.Status != tt.want {
+				t.Fatalf("stored status = %s, want %s", stored.Status, tt.want)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"restapi/internal/auth"
+	"restapi/internal/storage"
+	"restapi/internal/user"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Tokens — пара токенов, выдаваемая при входе и обновлении
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int64
+}
+
+// RefreshTokens обменивает refresh-токен на новую пару; старый токен гасится (ротация)
+func (s *Service) RefreshTokens(ctx context.Context, refreshToken string) (Tokens, error) {
+	userID, familyID, err := s.StorageImpl.UseRefreshToken(ctx, hashToken(refreshToken))
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrTokenReused):
+			s.log(ctx).Warnf("Refresh token reuse detected, token family revoked")
+			return Tokens{}, ErrInvalidToken
+		case errors.Is(err, storage.ErrTokenNotFound):
+			return Tokens{}, ErrInvalidToken
+		}
+		s.log(ctx).Errorf("Failed to use refresh token: %v", err)
+		return Tokens{}, err
+	}
+	s.log(ctx).Infof("Refreshing tokens for user ID: %d", userID)
+	u, err := s.StorageImpl.GetUserByID(ctx, userID)
+	if err != nil {
+		s.log(ctx).Errorf("Failed to load user for token refresh: %v", err)
+		return Tokens{}, err
+	}
+	if u.Banned() {
+		s.log(ctx).Warnf("Refresh rejected for banned user ID: %d", userID)
+		return Tokens{}, ErrUserBanned
+	}
+	return s.issueTokens(ctx, u, familyID)
+}
+
+// Logout отзывает текущий access-токен и, если передан, все семейство refresh-токена.
+// Refresh-токен другого пользователя не отзывается: иначе по утекшему токену можно разлогинить его владельца
+func (s *Service) Logout(ctx context.Context, userID int, jti string, expiresAt time.Time, refreshToken string) error {
+	s.log(ctx).Infof("Logging out user ID: %d", userID)
+	if err := s.StorageImpl.RevokeAccessToken(ctx, jti, expiresAt); err != nil {
+		s.log(ctx).Errorf("Failed to revoke access token: %v", err)
+		return err
+	}
+	if refreshToken == "" {
+		return nil
+	}
+	if err := s.StorageImpl.RevokeRefreshTokenFamily(ctx, userID, hashToken(refreshToken)); err != nil {
+		s.log(ctx).Errorf("Failed to revoke refresh token: %v", err)
+		return err
+	}
+	return nil
+}
+
+// issueTokens подписывает access-токен и сохраняет новый refresh-токен в семействе familyID
+func (s *Service) issueTokens(ctx context.Context, u user.User, familyID string) (Tokens, error) {
+	jti, err := randomToken(16)
+	if err != nil {
+		s.log(ctx).Errorf("Failed to generate token ID: %v", err)
+		return Tokens{}, err
+	}
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": u.ID,
+		"login":   u.Login,
+		"roles":   userRoles(u),
+		"jti":     jti,
+		"iat":     now.Unix(),
+		"exp":     now.Add(s.AccessTokenTTL).Unix(),
+	})
+	s.log(ctx).Debugf("Generated token claims: %v", token.Claims)
+	accessToken, err := token.SignedString([]byte(JWTKey))
+	if err != nil {
+		s.log(ctx).Errorf("Failed to generate token: %v", err)
+		return Tokens{}, err
+	}
+
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		s.log(ctx).Errorf("Failed to generate refresh token: %v", err)
+		return Tokens{}, err
+	}
+	if err := s.StorageImpl.CreateRefreshToken(ctx, u.ID, hashToken(refreshToken), familyID, now.Add(s.RefreshTokenTTL)); err != nil {
+		s.log(ctx).Errorf("Failed to store refresh token: %v", err)
+		return Tokens{}, err
+	}
+
+	return Tokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// userRoles возвращает роли пользователя для JWT; пустая роль старых записей считается обычной
+func userRoles(u user.User) []string {
+	if u.Role == "" {
+		return []string{auth.RoleUser}
+	}
+	return []string{u.Role}
+}
+
+// randomToken возвращает n случайных байт в base64url
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken — в БД хранится только SHA-256 от refresh-токена
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
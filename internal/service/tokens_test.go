@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"restapi/internal/hasher"
+	"testing"
+	"time"
+)
+
+// loginTestUser регистрирует пользователя и возвращает выданную при входе пару токенов
+func loginTestUser(t *testing.T, svc *Service, login string) Tokens {
+	t.Helper()
+	ctx := context.Background()
+	if _, err := svc.RegisterUser(ctx, login, "secret1"); err != nil {
+		t.Fatalf("RegisterUser: %v", err)
+	}
+	tokens, err := svc.LoginUser(ctx, login, "secret1")
+	if err != nil {
+		t.Fatalf("LoginUser: %v", err)
+	}
+	return tokens
+}
+
+func TestRefreshTokensRotation(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestService(t, hasher.Bcrypt)
+	first := loginTestUser(t, svc, "alice")
+
+	second, err := svc.RefreshTokens(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("RefreshTokens: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken || second.AccessToken == "" {
+		t.Fatalf("refresh did not issue a new pair: %+v", second)
+	}
+	third, err := svc.RefreshTokens(ctx, second.RefreshToken)
+	if err != nil {
+		t.Fatalf("RefreshTokens with rotated token: %v", err)
+	}
+
+	// повторное предъявление уже погашенного токена считается кражей и гасит все семейство
+	if _, err := svc.RefreshTokens(ctx, first.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("reused token = %v, want ErrInvalidToken", err)
+	}
+	if _, err := svc.RefreshTokens(ctx, third.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("latest token after reuse = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestRefreshTokensFamiliesAreIndependent(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestService(t, hasher.Bcrypt)
+	laptop := loginTestUser(t, svc, "alice")
+	phone, err := svc.LoginUser(ctx, "alice", "secret1")
+	if err != nil {
+		t.Fatalf("LoginUser: %v", err)
+	}
+
+	if _, err := svc.RefreshTokens(ctx, laptop.RefreshToken); err != nil {
+		t.Fatalf("RefreshTokens: %v", err)
+	}
+	if _, err := svc.RefreshTokens(ctx, laptop.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("reused token = %v, want ErrInvalidToken", err)
+	}
+	// отзыв семейства одного входа не затрагивает другие сессии пользователя
+	if _, err := svc.RefreshTokens(ctx, phone.RefreshToken); err != nil {
+		t.Fatalf("RefreshTokens of another session: %v", err)
+	}
+}
+
+func TestRefreshTokensRejected(t *testing.T) {
+	ctx := context.Background()
+	svc, store := newTestService(t, hasher.Bcrypt)
+	tokens := loginTestUser(t, svc, "alice")
+	// блокировка отзывает refresh-токены пользователя
+	banned := loginTestUser(t, svc, "bob")
+	bob, _ := store.GetUserByLogin(ctx, "bob")
+	if err := store.SetUserBanned(ctx, bob.ID, true); err != nil {
+		t.Fatalf("SetUserBanned: %v", err)
+	}
+	loggedOut := loginTestUser(t, svc, "carol")
+	carol, _ := store.GetUserByLogin(ctx, "carol")
+	if err := svc.Logout(ctx, carol.ID, "jti", time.Now().Add(time.Minute), loggedOut.RefreshToken); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"unknown token", "not-a-token", ErrInvalidToken},
+		{"access token instead of refresh", tokens.AccessToken, ErrInvalidToken},
+		{"banned user", banned.RefreshToken, ErrInvalidToken},
+		{"after logout", loggedOut.RefreshToken, ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := svc.RefreshTokens(ctx, tt.token); !errors.Is(err, tt.want) {
+				t.Fatalf("RefreshTokens = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogoutIgnoresForeignRefreshToken(t *testing.T) {
+	ctx := context.Background()
+	svc, store := newTestService(t, hasher.Bcrypt)
+	alice := loginTestUser(t, svc, "alice")
+	loginTestUser(t, svc, "bob")
+	bob, err := store.GetUserByLogin(ctx, "bob")
+	if err != nil {
+		t.Fatalf("GetUserByLogin: %v", err)
+	}
+
+	// bob передает чужой refresh-токен: сессия alice не должна пострадать
+	if err := svc.Logout(ctx, bob.ID, "bob-jti", time.Now().Add(time.Minute), alice.RefreshToken); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if _, err := svc.RefreshTokens(ctx, alice.RefreshToken); err != nil {
+		t.Fatalf("RefreshTokens after foreign logout: %v", err)
+	}
+}
@@ -0,0 +1,1081 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"restapi/internal/migrate"
+	"restapi/internal/models"
+	"restapi/internal/user"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type memoryUser struct {
+	id           int
+	login        string
+	passwordHash string
+	role         string
+	createdAt    time.Time
+	bannedAt     *time.Time
+}
+
+func (u *memoryUser) toModel() user.User {
+	return user.User{ID: u.id, Login: u.login, Role: u.role, CreatedAt: u.createdAt, BannedAt: u.bannedAt}
+}
+
+type memoryRefreshToken struct {
+	userID    int
+	familyID  string
+	expiresAt time.Time
+	revoked   bool
+}
+
+// scoredAd — объявление с релевантностью поиска для сортировки в GetAds
+type scoredAd struct {
+	*memoryAd
+	score int
+}
+
+type memoryAd struct {
+	ad        models.Ad
+	createdAt time.Time
+	// images хранится по порядку: индекс совпадает с position
+	images       []models.AdImage
+	priceHistory []models.PricePoint
+}
+
+// memoryFavorite — цена, которую пользователь видел последней, и его порог для уведомлений о снижении
+type memoryFavorite struct {
+	lastSeenPrice  float64
+	priceThreshold *float64
+}
+
+// setImages перенумеровывает галерею и обновляет обложку
+// requeueAfterContentChange отправляет опубликованное или отклоненное объявление на повторную проверку
+func (a *memoryAd) requeueAfterContentChange() {
+	if next := models.StatusAfterContentChange(a.ad.Status); next != a.ad.Status {
+		a.ad.Status = next
+		a.ad.RejectionReason = ""
+	}
+}
+
+func (a *memoryAd) setImages(images []models.AdImage) {
+	for i := range images {
+		images[i].Position = i
+	}
+	a.images = images
+	a.ad.ImageURL = ""
+	if len(images) > 0 {
+		a.ad.ImageURL = images[0].URL
+	}
+}
+
+type memoryThread struct {
+	id, adID, buyerID, sellerID   int
+	buyerLastRead, sellerLastRead int
+	createdAt, lastMessageAt      time.Time
+	messages                      []models.Message
+}
+
+// StorageMemory хранит данные в памяти процесса; подходит для тестов и локального запуска без PostgreSQL
+type StorageMemory struct {
+	mu         sync.RWMutex
+	users      map[int]*memoryUser
+	logins     map[string]int
+	ads        map[int]*memoryAd
+	nextUserID int
+	nextAdID   int
+	nextImgID  int
+	nextThrID  int
+	nextMsgID  int
+	// nextSearchID и nextNoteID — счетчики ID сохраненных поисков и уведомлений
+	nextSearchID  int
+	nextNoteID    int
+	savedSearches []models.SavedSearch
+	notifications []models.Notification
+	threads       map[int]*memoryThread
+	// favorites: ID объявления -> пользователи, добавившие его в избранное
+	favorites map[int]map[int]memoryFavorite
+	// refreshTokens индексируется хешем токена, revokedTokens — jti со сроком действия
+	refreshTokens map[string]*memoryRefreshToken
+	revokedTokens map[string]time.Time
+	categories    []models.Category
+}
+
+var _ Storage = (*StorageMemory)(nil)
+
+func NewMemory() *StorageMemory {
+	return &StorageMemory{
+		users:  make(map[int]*memoryUser),
+		logins: make(map[string]int),
+		ads:    make(map[int]*memoryAd),
+
+		threads:       make(map[int]*memoryThread),
+		favorites:     make(map[int]map[int]memoryFavorite),
+		refreshTokens: make(map[string]*memoryRefreshToken),
+		revokedTokens: make(map[string]time.Time),
+		categories:    slices.Clone(defaultCategories),
+	}
+}
+
+// defaultCategories повторяет дерево категорий из миграции 0007_categories
+var defaultCategories = []models.Category{
+	{ID: 1, Name: "Электроника", Slug: "electronics"},
+	{ID: 2, Name: "Транспорт", Slug: "transport"},
+	{ID: 3, Name: "Недвижимость", Slug: "real-estate"},
+	{ID: 4, Name: "Дом и сад", Slug: "home-garden"},
+	{ID: 5, Name: "Одежда и обувь", Slug: "clothing"},
+	{ID: 6, Name: "Хобби и отдых", Slug: "hobby"},
+	{ID: 7, ParentID: 1, Name: "Телефоны", Slug: "phones"},
+	{ID: 8, ParentID: 1, Name: "Ноутбуки и компьютеры", Slug: "computers"},
+	{ID: 9, ParentID: 1, Name: "Фото и видео", Slug: "photo-video"},
+	{ID: 10, ParentID: 2, Name: "Автомобили", Slug: "cars"},
+	{ID: 11, ParentID: 2, Name: "Велосипеды", Slug: "bicycles"},
+	{ID: 12, ParentID: 2, Name: "Запчасти", Slug: "auto-parts"},
+	{ID: 13, ParentID: 3, Name: "Квартиры", Slug: "apartments"},
+	{ID: 14, ParentID: 3, Name: "Дома", Slug: "houses"},
+	{ID: 15, ParentID: 4, Name: "Мебель", Slug: "furniture"},
+	{ID: 16, ParentID: 4, Name: "Бытовая техника", Slug: "appliances"},
+	{ID: 17, ParentID: 6, Name: "Спорт", Slug: "sports"},
+	{ID: 18, ParentID: 6, Name: "Книги", Slug: "books"},
+}
+
+// Close ничего не делает: у хранилища в памяти нет внешних ресурсов
+func (m *StorageMemory) Close() error {
+	return nil
+}
+
+// Ping всегда успешен: хранилище в памяти доступно, пока жив процесс
+func (m *StorageMemory) Ping(ctx context.Context) error {
+	return nil
+}
+
+// SchemaVersion возвращает последнюю версию миграций: схемы в памяти всегда актуальны
+func (m *StorageMemory) SchemaVersion(ctx context.Context) (int, error) {
+	return migrate.Latest(), nil
+}
+
+func (m *StorageMemory) RegisterUser(ctx context.Context, login, passwordHash string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.logins[login]; ok {
+		return 0, ErrLoginExists
+	}
+	m.nextUserID++
+	m.users[m.nextUserID] = &memoryUser{
+		id:           m.nextUserID,
+		login:        login,
+		passwordHash: passwordHash,
+		role:         "user",
+		createdAt:    time.Now().UTC(),
+	}
+	m.logins[login] = m.nextUserID
+	return m.nextUserID, nil
+}
+
+// GetUserByLogin возвращает пользователя вместе с сохраненным хешем пароля (поле Password)
+func (m *StorageMemory) GetUserByLogin(ctx context.Context, login string) (user.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	userID, ok := m.logins[login]
+	if !ok {
+		return user.User{}, ErrUserNotFound
+	}
+	u := m.users[userID].toModel()
+	u.Password = m.users[userID].passwordHash
+	return u, nil
+}
+
+// GetUserByID возвращает пользователя по ID (без хеша пароля)
+func (m *StorageMemory) GetUserByID(ctx context.Context, userID int) (user.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	u, ok := m.users[userID]
+	if !ok {
+		return user.User{}, ErrUserNotFound
+	}
+	return u.toModel(), nil
+}
+
+// ListUsers возвращает страницу пользователей по возрастанию ID и их общее число
+func (m *StorageMemory) ListUsers(ctx context.Context, limit, offset int) ([]user.User, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	ids := make([]int, 0, len(m.users))
+	for id := range m.users {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	if offset >= len(ids) {
+		return nil, len(ids), nil
+	}
+	end := min(offset+limit, len(ids))
+	users := make([]user.User, 0, end-offset)
+	for _, id := range ids[offset:end] {
+		users = append(users, m.users[id].toModel())
+	}
+	return users, len(ids), nil
+}
+
+// SetUserRole меняет роль пользователя
+func (m *StorageMemory) SetUserRole(ctx context.Context, userID int, role string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, ok := m.users[userID]
+	if !ok {
+		return ErrUserNotFound
+	}
+	u.role = role
+	return nil
+}
+
+// SetUserBanned блокирует или разблокирует пользователя; при блокировке отзываются все его refresh-токены
+func (m *StorageMemory) SetUserBanned(ctx context.Context, userID int, banned bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, ok := m.users[userID]
+	if !ok {
+		return ErrUserNotFound
+	}
+	if !banned {
+		u.bannedAt = nil
+		return nil
+	}
+	if u.bannedAt == nil {
+		now := time.Now().UTC()
+		u.bannedAt = &now
+	}
+	for _, t := range m.refreshTokens {
+		if t.userID == userID {
+			t.revoked = true
+		}
+	}
+	return nil
+}
+
+// UpdatePasswordHash заменяет сохраненный хеш пароля
+func (m *StorageMemory) UpdatePasswordHash(ctx context.Context, userID int, passwordHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, ok := m.users[userID]
+	if !ok {
+		return ErrUserNotFound
+	}
+	u.passwordHash = passwordHash
+	return nil
+}
+
+func (m *StorageMemory) CreateAd(ctx context.Context, userID int, title, description string, categoryID int, images []string, price float64, status string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[userID]; !ok {
+		return 0, fmt.Errorf("user with ID %d does not exist", userID)
+	}
+	m.nextAdID++
+	createdAt := time.Now().UTC()
+	a := &memoryAd{
+		ad: models.Ad{
+			ID:          m.nextAdID,
+			Title:       title,
+			Description: description,
+			Price:       price,
+			UserID:      userID,
+			Status:      status,
+			CategoryID:  categoryID,
+		},
+		createdAt:    createdAt,
+		priceHistory: []models.PricePoint{{Price: price, ChangedAt: createdAt.Format(time.RFC3339)}},
+	}
+	a.setImages(m.newImages(images))
+	m.ads[m.nextAdID] = a
+	return m.nextAdID, nil
+}
+
+func (m *StorageMemory) GetAds(ctx context.Context, filter models.AdsFilter, viewer models.Viewer) ([]models.Ad, error) {
+	filter = filter.Normalize()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	matched := m.matchAds(filter, viewer)
+	// compare возвращает -1/0/1 для порядка по возрастанию ключа сортировки с id как tie-breaker
+	compare := func(a, b scoredAd) int {
+		var c int
+		switch filter.SortBy {
+		case models.SortByPrice:
+			c = cmp.Compare(a.ad.Price, b.ad.Price)
+		case models.SortByRelevance:
+			c = cmp.Compare(a.score, b.score)
+		default:
+			c = a.createdAt.Compare(b.createdAt)
+		}
+		if c == 0 {
+			c = cmp.Compare(a.ad.ID, b.ad.ID)
+		}
+		if filter.SortOrder == "DESC" {
+			return -c
+		}
+		return c
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return compare(matched[i], matched[j]) < 0
+	})
+
+	offset := (filter.Page - 1) * filter.PageSize
+	if c := filter.Cursor; c != nil {
+		pos, err := memoryCursorPosition(filter, c)
+		if err != nil {
+			return nil, err
+		}
+		// первый элемент строго после курсора в порядке ленты
+		first := sort.Search(len(matched), func(i int) bool { return compare(matched[i], pos) > 0 })
+		if c.Backward {
+			// страница перед курсором: элементы строго до него
+			before := sort.Search(len(matched), func(i int) bool { return compare(matched[i], pos) >= 0 })
+			start := before - filter.PageSize
+			if start < 0 {
+				start = 0
+			}
+			matched = matched[start:before]
+		} else {
+			matched = matched[first:]
+		}
+		offset = 0
+	}
+	if offset >= len(matched) {
+		return nil, nil
+	}
+	end := offset + filter.PageSize
+	if end > len(matched) {
+		end = len(matched)
+	}
+
+	ads := make([]models.Ad, 0, end-offset)
+	for _, a := range matched[offset:end] {
+		ad := m.toModel(a.memoryAd)
+		ad.IsOwner = viewer.UserID != 0 && ad.UserID == viewer.UserID
+		_, ad.IsFavorite = m.favorites[ad.ID][viewer.UserID]
+		ads = append(ads, ad)
+	}
+	return ads, nil
+}
+
+// CountAds возвращает число объявлений, подходящих под фильтр (без учета пагинации)
+func (m *StorageMemory) CountAds(ctx context.Context, filter models.AdsFilter, viewer models.Viewer) (int, error) {
+	filter = filter.Normalize()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.matchAds(filter, viewer)), nil
+}
+
+// matchAds отбирает объявления по статусу, цене и поисковому запросу; вызывается под m.mu
+func (m *StorageMemory) matchAds(filter models.AdsFilter, viewer models.Viewer) []scoredAd {
+	search := parseMemorySearch(filter.Query)
+	var matched []scoredAd
+	for _, a := range m.ads {
+		if filter.Status != "" && a.ad.Status != filter.Status {
+			continue
+		}
+		if filter.Status == "" && a.ad.Status != models.AdStatusPublished && a.ad.UserID != viewer.UserID {
+			continue
+		}
+		if a.ad.Price < filter.MinPrice || a.ad.Price > filter.MaxPrice {
+			continue
+		}
+		if len(filter.CategoryIDs) > 0 && !slices.Contains(filter.CategoryIDs, a.ad.CategoryID) {
+			continue
+		}
+		if _, ok := m.favorites[a.ad.ID][filter.FavoritesOf]; filter.FavoritesOf != 0 && !ok {
+			continue
+		}
+		if filter.AdID != 0 && a.ad.ID != filter.AdID {
+			continue
+		}
+		score, ok := search.score(a.ad.Title, a.ad.Description)
+		if !ok {
+			continue
+		}
+		matched = append(matched, scoredAd{memoryAd: a, score: score})
+	}
+	return matched
+}
+
+// memoryCursorPosition превращает курсор в фиктивную запись для сравнения с объявлениями
+func memoryCursorPosition(filter models.AdsFilter, c *models.AdCursor) (scoredAd, error) {
+	pos := scoredAd{memoryAd: &memoryAd{ad: models.Ad{ID: c.ID}}}
+	switch filter.SortBy {
+	case models.SortByCreatedAt:
+		createdAt, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return pos, fmt.Errorf("invalid cursor value: %v", err)
+		}
+		pos.createdAt = createdAt
+	case models.SortByPrice:
+		price, err := strconv.ParseFloat(c.Value, 64)
+		if err != nil {
+			return pos, fmt.Errorf("invalid cursor value: %v", err)
+		}
+		pos.ad.Price = price
+	default:
+		return pos, fmt.Errorf("cursor pagination is not supported for sort_by=%s", filter.SortBy)
+	}
+	return pos, nil
+}
+
+// GetAd возвращает объявление по ID
+func (m *StorageMemory) GetAd(ctx context.Context, adID int) (models.Ad, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	a, ok := m.ads[adID]
+	if !ok {
+		return models.Ad{}, ErrAdNotFound
+	}
+	ad := m.toModel(a)
+	ad.Images = slices.Clone(a.images)
+	return ad, nil
+}
+
+// UpdateAd сохраняет изменяемые поля объявления; непустой images заменяет галерею, nil оставляет ее как есть.
+// При contentChanged опубликованное или отклоненное объявление уходит на проверку; архивное не меняется
+func (m *StorageMemory) UpdateAd(ctx context.Context, ad models.Ad, images []string, contentChanged bool) (models.AdUpdateResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	a, ok := m.ads[ad.ID]
+	if !ok {
+		return models.AdUpdateResult{}, ErrAdNotFound
+	}
+	if a.ad.Status == models.AdStatusArchived {
+		return models.AdUpdateResult{}, ErrAdStatusConflict
+	}
+	res := models.AdUpdateResult{PrevStatus: a.ad.Status, Status: a.ad.Status, OldPrice: a.ad.Price}
+	if contentChanged {
+		res.Status = models.StatusAfterContentChange(a.ad.Status)
+	}
+	a.ad.Title = ad.Title
+	a.ad.Description = ad.Description
+	a.ad.ImageURL = ad.ImageURL
+	if a.ad.Price != ad.Price {
+		a.priceHistory = append(a.priceHistory, models.PricePoint{Price: ad.Price, ChangedAt: time.Now().UTC().Format(time.RFC3339)})
+	}
+	a.ad.Price = ad.Price
+	if res.Status != a.ad.Status {
+		a.ad.Status = res.Status
+		a.ad.RejectionReason = ""
+	}
+	res.RejectionReason = a.ad.RejectionReason
+	a.ad.CategoryID = ad.CategoryID
+	if images != nil {
+		a.setImages(m.newImages(images))
+		res.Images = slices.Clone(a.images)
+	}
+	return res, nil
+}
+
+// TransitionAd переводит объявление в статус to, если текущий статус входит в from
+func (m *StorageMemory) TransitionAd(ctx context.Context, adID int, from []string, to, reason string, moderatorID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	a, ok := m.ads[adID]
+	if !ok {
+		return ErrAdNotFound
+	}
+	if !slices.Contains(from, a.ad.Status) {
+		return ErrAdStatusConflict
+	}
+	a.ad.Status = to
+	a.ad.RejectionReason = reason
+	return nil
+}
+
+// DeleteAd удаляет объявление
+func (m *StorageMemory) DeleteAd(ctx context.Context, adID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.ads[adID]; !ok {
+		return ErrAdNotFound
+	}
+	delete(m.ads, adID)
+	// как ON DELETE CASCADE в PostgreSQL
+	delete(m.favorites, adID)
+	for id, t := range m.threads {
+		if t.adID == adID {
+			delete(m.threads, id)
+		}
+	}
+	m.notifications = slices.DeleteFunc(m.notifications, func(n models.Notification) bool { return n.AdID == adID })
+	return nil
+}
+
+// AddAdImage добавляет изображение в конец галереи, если в ней меньше maxImages изображений.
+// Опубликованное или отклоненное объявление уходит на повторную проверку
+func (m *StorageMemory) AddAdImage(ctx context.Context, adID int, url string, maxImages int) (models.AdImage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	a, ok := m.ads[adID]
+	if !ok {
+		return models.AdImage{}, ErrAdNotFound
+	}
+	if a.ad.Status == models.AdStatusArchived {
+		return models.AdImage{}, ErrAdStatusConflict
+	}
+	if len(a.images) >= maxImages {
+		return models.AdImage{}, ErrAdImageLimit
+	}
+	a.setImages(append(slices.Clone(a.images), m.newImages([]string{url})...))
+	a.requeueAfterContentChange()
+	return a.images[len(a.images)-1], nil
+}
+
+// DeleteAdImage удаляет изображение из галереи и сдвигает следующие за ним.
+// Опубликованное или отклоненное объявление уходит на повторную проверку
+func (m *StorageMemory) DeleteAdImage(ctx context.Context, adID, imageID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	a, ok := m.ads[adID]
+	if !ok {
+		return ErrAdNotFound
+	}
+	if a.ad.Status == models.AdStatusArchived {
+		return ErrAdStatusConflict
+	}
+	i := slices.IndexFunc(a.images, func(img models.AdImage) bool { return img.ID == imageID })
+	if i < 0 {
+		return ErrAdImageNotFound
+	}
+	a.setImages(slices.Delete(slices.Clone(a.images), i, i+1))
+	a.requeueAfterContentChange()
+	return nil
+}
+
+// ReorderAdImages расставляет изображения в порядке imageIDs; imageIDs должен содержать всю галерею.
+// Если порядок изменился, опубликованное или отклоненное объявление уходит на повторную проверку
+func (m *StorageMemory) ReorderAdImages(ctx context.Context, adID int, imageIDs []int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	a, ok := m.ads[adID]
+	if !ok {
+		return ErrAdNotFound
+	}
+	if a.ad.Status == models.AdStatusArchived {
+		return ErrAdStatusConflict
+	}
+	images := make([]models.AdImage, 0, len(imageIDs))
+	for _, id := range imageIDs {
+		i := slices.IndexFunc(a.images, func(img models.AdImage) bool { return img.ID == id })
+		if i < 0 {
+			return ErrAdImageNotFound
+		}
+		images = append(images, a.images[i])
+	}
+	if slices.EqualFunc(images, a.images, func(x, y models.AdImage) bool { return x.ID == y.ID }) {
+		return nil
+	}
+	a.setImages(images)
+	a.requeueAfterContentChange()
+	return nil
+}
+
+// GetCategories возвращает все категории плоским списком в порядке показа
+func (m *StorageMemory) GetCategories(ctx context.Context) ([]models.Category, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return slices.Clone(m.categories), nil
+}
+
+// AddFavorite добавляет объявление в избранное и запоминает текущую цену как увиденную.
+// Повторное добавление обновляет увиденную цену и порог; nil priceThreshold убирает порог
+func (m *StorageMemory) AddFavorite(ctx context.Context, userID, adID int, priceThreshold *float64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	a, ok := m.ads[adID]
+	if !ok {
+		return ErrAdNotFound
+	}
+	if m.favorites[adID] == nil {
+		m.favorites[adID] = make(map[int]memoryFavorite)
+	}
+	m.favorites[adID][userID] = memoryFavorite{lastSeenPrice: a.ad.Price, priceThreshold: priceThreshold}
+	return nil
+}
+
+// RemoveFavorite убирает объявление из избранного; отсутствие записи не ошибка
+func (m *StorageMemory) RemoveFavorite(ctx context.Context, userID, adID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.favorites[adID], userID)
+	return nil
+}
+
+// IsFavorite сообщает, добавил ли пользователь объявление в избранное
+func (m *StorageMemory) IsFavorite(ctx context.Context, userID, adID int) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.favorites[adID][userID]
+	return ok, nil
+}
+
+// ClaimPriceDropWatchers возвращает пользователей, для которых новая цена — снижение: она ниже увиденной
+// и, если задан порог, не выше его, вместе с прежней увиденной ценой.
+// Для них price сразу запоминается как увиденная, чтобы не уведомлять повторно
+func (m *StorageMemory) ClaimPriceDropWatchers(ctx context.Context, adID int, price float64) ([]models.PriceWatcher, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var watchers []models.PriceWatcher
+	for userID, f := range m.favorites[adID] {
+		if price >= f.lastSeenPrice || (f.priceThreshold != nil && price > *f.priceThreshold) {
+			continue
+		}
+		watchers = append(watchers, models.PriceWatcher{UserID: userID, LastSeenPrice: f.lastSeenPrice})
+		f.lastSeenPrice = price
+		m.favorites[adID][userID] = f
+	}
+	slices.SortFunc(watchers, func(a, b models.PriceWatcher) int { return a.UserID - b.UserID })
+	return watchers, nil
+}
+
+// GetPriceHistory возвращает историю цены объявления от старых записей к новым
+func (m *StorageMemory) GetPriceHistory(ctx context.Context, adID int) ([]models.PricePoint, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	a, ok := m.ads[adID]
+	if !ok {
+		return nil, ErrAdNotFound
+	}
+	return slices.Clone(a.priceHistory), nil
+}
+
+// GetOrCreateThread возвращает ветку покупателя по объявлению, создавая ее при первом обращении
+func (m *StorageMemory) GetOrCreateThread(ctx context.Context, adID, buyerID, sellerID int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.ads[adID]; !ok {
+		return 0, ErrAdNotFound
+	}
+	for _, t := range m.threads {
+		if t.adID == adID && t.buyerID == buyerID {
+			return t.id, nil
+		}
+	}
+	m.nextThrID++
+	now := time.Now().UTC()
+	m.threads[m.nextThrID] = &memoryThread{id: m.nextThrID, adID: adID, buyerID: buyerID, sellerID: sellerID, createdAt: now, lastMessageAt: now}
+	return m.nextThrID, nil
+}
+
+// GetThread возвращает ветку; непрочитанные считаются для userID
+func (m *StorageMemory) GetThread(ctx context.Context, threadID, userID int) (models.Thread, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	t, ok := m.threads[threadID]
+	if !ok {
+		return models.Thread{}, ErrThreadNotFound
+	}
+	return m.threadModel(t, userID), nil
+}
+
+// ListThreads возвращает ветки пользователя (как покупателя и как продавца), свежие первыми, и их общее число
+func (m *StorageMemory) ListThreads(ctx context.Context, userID, limit, offset int) ([]models.Thread, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var mine []*memoryThread
+	for _, t := range m.threads {
+		if t.buyerID == userID || t.sellerID == userID {
+			mine = append(mine, t)
+		}
+	}
+	slices.SortFunc(mine, func(a, b *memoryThread) int {
+		if c := b.lastMessageAt.Compare(a.lastMessageAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.id, a.id)
+	})
+	var threads []models.Thread
+	for i := offset; i < len(mine) && i < offset+limit; i++ {
+		threads = append(threads, m.threadModel(mine[i], userID))
+	}
+	return threads, len(mine), nil
+}
+
+// AddMessage сохраняет сообщение; своя ветка у отправителя считается прочитанной
+func (m *StorageMemory) AddMessage(ctx context.Context, threadID, senderID int, body string) (models.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.threads[threadID]
+	if !ok {
+		return models.Message{}, ErrThreadNotFound
+	}
+	m.nextMsgID++
+	now := time.Now().UTC()
+	msg := models.Message{ID: m.nextMsgID, ThreadID: threadID, SenderID: senderID, Body: body, CreatedAt: now.Format(time.RFC3339)}
+	t.messages = append(t.messages, msg)
+	t.lastMessageAt = now
+	if t.buyerID == senderID {
+		t.buyerLastRead = msg.ID
+	}
+	if t.sellerID == senderID {
+		t.sellerLastRead = msg.ID
+	}
+	return msg, nil
+}
+
+// GetMessages возвращает сообщения ветки от старых к новым и их общее число
+func (m *StorageMemory) GetMessages(ctx context.Context, threadID, limit, offset int) ([]models.Message, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	t, ok := m.threads[threadID]
+	if !ok {
+		return nil, 0, nil
+	}
+	var messages []models.Message
+	for i := offset; i < len(t.messages) && i < offset+limit; i++ {
+		messages = append(messages, t.messages[i])
+	}
+	return messages, len(t.messages), nil
+}
+
+// MarkThreadRead отмечает все сообщения ветки прочитанными для userID
+func (m *StorageMemory) MarkThreadRead(ctx context.Context, threadID, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.threads[threadID]
+	if !ok {
+		return ErrThreadNotFound
+	}
+	lastID := 0
+	if len(t.messages) > 0 {
+		lastID = t.messages[len(t.messages)-1].ID
+	}
+	if t.buyerID == userID {
+		t.buyerLastRead = lastID
+	}
+	if t.sellerID == userID {
+		t.sellerLastRead = lastID
+	}
+	return nil
+}
+
+// CreateSavedSearch сохраняет поиск пользователя, если у него меньше maxSearches поисков
+func (m *StorageMemory) CreateSavedSearch(ctx context.Context, search models.SavedSearch, maxSearches int) (models.SavedSearch, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[search.UserID]; !ok {
+		return models.SavedSearch{}, ErrUserNotFound
+	}
+	count := 0
+	for _, s := range m.savedSearches {
+		if s.UserID == search.UserID {
+			count++
+		}
+	}
+	if count >= maxSearches {
+		return models.SavedSearch{}, ErrSavedSearchLimit
+	}
+	m.nextSearchID++
+	search.ID = m.nextSearchID
+	search.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	m.savedSearches = append(m.savedSearches, search)
+	return search, nil
+}
+
+// ListSavedSearches возвращает поиски пользователя в порядке создания
+func (m *StorageMemory) ListSavedSearches(ctx context.Context, userID int) ([]models.SavedSearch, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var searches []models.SavedSearch
+	for _, s := range m.savedSearches {
+		if s.UserID == userID {
+			searches = append(searches, s)
+		}
+	}
+	return searches, nil
+}
+
+// DeleteSavedSearch удаляет поиск пользователя
+func (m *StorageMemory) DeleteSavedSearch(ctx context.Context, userID, searchID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := slices.IndexFunc(m.savedSearches, func(s models.SavedSearch) bool { return s.ID == searchID && s.UserID == userID })
+	if i < 0 {
+		return ErrSavedSearchNotFound
+	}
+	m.savedSearches = slices.Delete(m.savedSearches, i, i+1)
+	// как ON DELETE SET NULL в PostgreSQL
+	for i := range m.notifications {
+		if m.notifications[i].SavedSearchID == searchID {
+			m.notifications[i].SavedSearchID = 0
+		}
+	}
+	return nil
+}
+
+// SavedSearchesForPrice возвращает поиски всех пользователей, в диапазон цен которых попадает price
+func (m *StorageMemory) SavedSearchesForPrice(ctx context.Context, price float64) ([]models.SavedSearch, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var searches []models.SavedSearch
+	for _, s := range m.savedSearches {
+		if price >= s.MinPrice && price <= s.MaxPrice {
+			searches = append(searches, s)
+		}
+	}
+	return searches, nil
+}
+
+// AddNotification сохраняет уведомление. false — такое совпадение с сохраненным поиском уже было, уведомление не создано
+func (m *StorageMemory) AddNotification(ctx context.Context, n models.Notification) (models.Notification, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	a, ok := m.ads[n.AdID]
+	if !ok {
+		return models.Notification{}, false, ErrAdNotFound
+	}
+	if n.SavedSearchID != 0 && slices.ContainsFunc(m.notifications, func(o models.Notification) bool {
+		return o.SavedSearchID == n.SavedSearchID && o.AdID == n.AdID
+	}) {
+		return models.Notification{}, false, nil
+	}
+	m.nextNoteID++
+	n.ID = m.nextNoteID
+	n.Read = false
+	n.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	m.notifications = append(m.notifications, n)
+	n.AdTitle = a.ad.Title
+	return n, true, nil
+}
+
+// ListNotifications возвращает уведомления пользователя, новые первыми, и их общее число
+func (m *StorageMemory) ListNotifications(ctx context.Context, userID int, unreadOnly bool, limit, offset int) ([]models.Notification, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var mine []models.Notification
+	for i := len(m.notifications) - 1; i >= 0; i-- {
+		n := m.notifications[i]
+		if n.UserID != userID || (unreadOnly && n.Read) {
+			continue
+		}
+		if a, ok := m.ads[n.AdID]; ok {
+			n.AdTitle = a.ad.Title
+		}
+		mine = append(mine, n)
+	}
+	var notifications []models.Notification
+	for i := offset; i < len(mine) && i < offset+limit; i++ {
+		notifications = append(notifications, mine[i])
+	}
+	return notifications, len(mine), nil
+}
+
+// CountUnreadNotifications возвращает число непрочитанных уведомлений пользователя
+func (m *StorageMemory) CountUnreadNotifications(ctx context.Context, userID int) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	unread := 0
+	for _, n := range m.notifications {
+		if n.UserID == userID && !n.Read {
+			unread++
+		}
+	}
+	return unread, nil
+}
+
+// MarkNotificationRead отмечает уведомление пользователя прочитанным; повторная отметка не ошибка
+func (m *StorageMemory) MarkNotificationRead(ctx context.Context, userID, notificationID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := slices.IndexFunc(m.notifications, func(n models.Notification) bool { return n.ID == notificationID && n.UserID == userID })
+	if i < 0 {
+		return ErrNotificationNotFound
+	}
+	m.notifications[i].Read = true
+	return nil
+}
+
+// MarkAllNotificationsRead отмечает прочитанными все уведомления пользователя
+func (m *StorageMemory) MarkAllNotificationsRead(ctx context.Context, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.notifications {
+		if m.notifications[i].UserID == userID {
+			m.notifications[i].Read = true
+		}
+	}
+	return nil
+}
+
+// threadModel собирает ветку с логинами, последним сообщением и непрочитанными для userID; вызывается под блокировкой
+func (m *StorageMemory) threadModel(t *memoryThread, userID int) models.Thread {
+	thread := models.Thread{
+		ID:        t.id,
+		AdID:      t.adID,
+		BuyerID:   t.buyerID,
+		SellerID:  t.sellerID,
+		CreatedAt: t.createdAt.Format(time.RFC3339),
+	}
+	if a, ok := m.ads[t.adID]; ok {
+		thread.AdTitle = a.ad.Title
+	}
+	if u, ok := m.users[t.buyerID]; ok {
+		thread.BuyerLogin = u.login
+	}
+	if u, ok := m.users[t.sellerID]; ok {
+		thread.SellerLogin = u.login
+	}
+	if len(t.messages) > 0 {
+		last := t.messages[len(t.messages)-1]
+		thread.LastMessage = &last
+	}
+	lastRead := t.sellerLastRead
+	if t.buyerID == userID {
+		lastRead = t.buyerLastRead
+	}
+	for _, msg := range t.messages {
+		if msg.SenderID != userID && msg.ID > lastRead {
+			thread.UnreadCount++
+		}
+	}
+	return thread
+}
+
+// newImages выдает ID новым изображениям галереи; вызывается под m.mu
+func (m *StorageMemory) newImages(urls []string) []models.AdImage {
+	images := make([]models.AdImage, 0, len(urls))
+	for _, url := range urls {
+		m.nextImgID++
+		images = append(images, models.AdImage{ID: m.nextImgID, URL: url})
+	}
+	return images
+}
+
+// toModel собирает копию объявления с логином автора; вызывается под блокировкой
+func (m *StorageMemory) toModel(a *memoryAd) models.Ad {
+	ad := a.ad
+	if u, ok := m.users[ad.UserID]; ok {
+		ad.Login = u.login
+	}
+	ad.CreatedAt = a.createdAt.Format(time.RFC3339)
+	ad.CreatedAtTime = a.createdAt
+	ad.FavoritesCount = len(m.favorites[ad.ID])
+	return ad
+}
+
+// CreateRefreshToken сохраняет хеш нового refresh-токена
+func (m *StorageMemory) CreateRefreshToken(ctx context.Context, userID int, tokenHash, familyID string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[userID]; !ok {
+		return ErrUserNotFound
+	}
+	m.refreshTokens[tokenHash] = &memoryRefreshToken{userID: userID, familyID: familyID, expiresAt: expiresAt}
+	return nil
+}
+
+// UseRefreshToken погашает refresh-токен; повторное использование отзывает все семейство
+func (m *StorageMemory) UseRefreshToken(ctx context.Context, tokenHash string) (int, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.refreshTokens[tokenHash]
+	if !ok {
+		return 0, "", ErrTokenNotFound
+	}
+	if t.revoked {
+		m.revokeFamily(t.familyID)
+		return 0, "", ErrTokenReused
+	}
+	if !t.expiresAt.After(time.Now()) {
+		return 0, "", ErrTokenNotFound
+	}
+	t.revoked = true
+	return t.userID, t.familyID, nil
+}
+
+// RevokeRefreshTokenFamily отзывает все refresh-токены из семейства указанного токена.
+// Токен другого пользователя игнорируется, как и неизвестный
+func (m *StorageMemory) RevokeRefreshTokenFamily(ctx context.Context, userID int, tokenHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if t, ok := m.refreshTokens[tokenHash]; ok && t.userID == userID {
+		m.revokeFamily(t.familyID)
+	}
+	return nil
+}
+
+// revokeFamily вызывается под блокировкой
+func (m *StorageMemory) revokeFamily(familyID string) {
+	for _, t := range m.refreshTokens {
+		if t.familyID == familyID {
+			t.revoked = true
+		}
+	}
+}
+
+// RevokeAccessToken заносит jti access-токена в denylist до истечения его срока
+func (m *StorageMemory) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for id, exp := range m.revokedTokens {
+		if exp.Before(now) {
+			delete(m.revokedTokens, id)
+		}
+	}
+	m.revokedTokens[jti] = expiresAt
+	return nil
+}
+
+// IsAccessTokenRevoked проверяет, есть ли jti в denylist
+func (m *StorageMemory) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.revokedTokens[jti]
+	return ok, nil
+}
+
+// memorySearch — упрощенный аналог websearch_to_tsquery для хранилища в памяти:
+// все слова должны встречаться в заголовке или описании, слова с "-" — отсутствовать
+type memorySearch struct {
+	include []string
+	exclude []string
+}
+
+func parseMemorySearch(query string) memorySearch {
+	var q memorySearch
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		exclude := strings.HasPrefix(word, "-")
+		word = strings.Trim(word, "-\"'.,!?()")
+		if word == "" || word == "or" {
+			continue
+		}
+		if exclude {
+			q.exclude = append(q.exclude, word)
+		} else {
+			q.include = append(q.include, word)
+		}
+	}
+	return q
+}
+
+// score возвращает релевантность (совпадения в заголовке весят больше) и признак совпадения
+func (q memorySearch) score(title, description string) (int, bool) {
+	title, description = strings.ToLower(title), strings.ToLower(description)
+	for _, word := range q.exclude {
+		if strings.Contains(title, word) || strings.Contains(description, word) {
+			return 0, false
+		}
+	}
+	score := 0
+	for _, word := range q.include {
+		inTitle, inDescription := strings.Count(title, word), strings.Count(description, word)
+		if inTitle+inDescription == 0 {
+			return 0, false
+		}
+		score += 2*inTitle + inDescription
+	}
+	return score, true
+}
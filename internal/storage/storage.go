@@ -3,19 +3,89 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"restapi/internal/models"
+	"restapi/internal/user"
+	"slices"
 	"strings"
 	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrLoginExists  = errors.New("login already exists")
+	ErrUserNotFound = errors.New("user not found")
+	ErrAdNotFound   = errors.New("ad not found")
+	// ErrAdStatusConflict возвращается, если текущий статус объявления не допускает перехода
+	ErrAdStatusConflict = errors.New("ad status does not allow this action")
+	// ErrAdImageNotFound возвращается, если у объявления нет изображения с таким ID
+	ErrAdImageNotFound = errors.New("ad image not found")
+	// ErrAdImageLimit возвращается, если галерея объявления уже заполнена
+	ErrAdImageLimit = errors.New("ad image limit reached")
+	// ErrThreadNotFound возвращается для неизвестной ветки переписки
+	ErrThreadNotFound = errors.New("thread not found")
+	// ErrSavedSearchNotFound возвращается для неизвестного или чужого сохраненного поиска
+	ErrSavedSearchNotFound = errors.New("saved search not found")
+	// ErrSavedSearchLimit возвращается, если у пользователя уже максимальное число сохраненных поисков
+	ErrSavedSearchLimit = errors.New("saved search limit reached")
+	// ErrNotificationNotFound возвращается для неизвестного или чужого уведомления
+	ErrNotificationNotFound = errors.New("notification not found")
+	// ErrTokenNotFound возвращается для неизвестного, отозванного при выходе или просроченного refresh-токена
+	ErrTokenNotFound = errors.New("token not found")
+	// ErrTokenReused возвращается при повторном использовании уже замененного refresh-токена
+	ErrTokenReused = errors.New("refresh token reuse detected")
 )
 
 type Storage interface {
-	RegisterUser(ctx context.Context, login, password string) (int, error)
-	CheckUser(ctx context.Context, login, password string) (int, error)
-	CreateAd(ctx context.Context, userID int, title, description, imageURL string, price float64) (int, error)
-	GetAds(ctx context.Context, page, pageSize int, sortBy, sortOrder string, minPrice, maxPrice float64) ([]models.Ad, error)
-	IsAdOwner(ctx context.Context, adID, userID int) (bool, error)
+	RegisterUser(ctx context.Context, login, passwordHash string) (int, error)
+	GetUserByLogin(ctx context.Context, login string) (user.User, error)
+	GetUserByID(ctx context.Context, userID int) (user.User, error)
+	ListUsers(ctx context.Context, limit, offset int) ([]user.User, int, error)
+	SetUserRole(ctx context.Context, userID int, role string) error
+	SetUserBanned(ctx context.Context, userID int, banned bool) error
+	UpdatePasswordHash(ctx context.Context, userID int, passwordHash string) error
+	CreateAd(ctx context.Context, userID int, title, description string, categoryID int, images []string, price float64, status string) (int, error)
+	GetAds(ctx context.Context, filter models.AdsFilter, viewer models.Viewer) ([]models.Ad, error)
+	CountAds(ctx context.Context, filter models.AdsFilter, viewer models.Viewer) (int, error)
+	GetAd(ctx context.Context, adID int) (models.Ad, error)
+	UpdateAd(ctx context.Context, ad models.Ad, images []string, contentChanged bool) (models.AdUpdateResult, error)
+	TransitionAd(ctx context.Context, adID int, from []string, to, reason string, moderatorID int) error
+	DeleteAd(ctx context.Context, adID int) error
+	AddAdImage(ctx context.Context, adID int, url string, maxImages int) (models.AdImage, error)
+	DeleteAdImage(ctx context.Context, adID, imageID int) error
+	ReorderAdImages(ctx context.Context, adID int, imageIDs []int) error
+	GetCategories(ctx context.Context) ([]models.Category, error)
+	AddFavorite(ctx context.Context, userID, adID int, priceThreshold *float64) error
+	RemoveFavorite(ctx context.Context, userID, adID int) error
+	IsFavorite(ctx context.Context, userID, adID int) (bool, error)
+	ClaimPriceDropWatchers(ctx context.Context, adID int, price float64) ([]models.PriceWatcher, error)
+	GetPriceHistory(ctx context.Context, adID int) ([]models.PricePoint, error)
+	GetOrCreateThread(ctx context.Context, adID, buyerID, sellerID int) (int, error)
+	GetThread(ctx context.Context, threadID, userID int) (models.Thread, error)
+	ListThreads(ctx context.Context, userID, limit, offset int) ([]models.Thread, int, error)
+	AddMessage(ctx context.Context, threadID, senderID int, body string) (models.Message, error)
+	GetMessages(ctx context.Context, threadID, limit, offset int) ([]models.Message, int, error)
+	MarkThreadRead(ctx context.Context, threadID, userID int) error
+	CreateSavedSearch(ctx context.Context, search models.SavedSearch, maxSearches int) (models.SavedSearch, error)
+	ListSavedSearches(ctx context.Context, userID int) ([]models.SavedSearch, error)
+	DeleteSavedSearch(ctx context.Context, userID, searchID int) error
+	SavedSearchesForPrice(ctx context.Context, price float64) ([]models.SavedSearch, error)
+	AddNotification(ctx context.Context, n models.Notification) (models.Notification, bool, error)
+	ListNotifications(ctx context.Context, userID int, unreadOnly bool, limit, offset int) ([]models.Notification, int, error)
+	CountUnreadNotifications(ctx context.Context, userID int) (int, error)
+	MarkNotificationRead(ctx context.Context, userID, notificationID int) error
+	MarkAllNotificationsRead(ctx context.Context, userID int) error
+	CreateRefreshToken(ctx context.Context, userID int, tokenHash, familyID string, expiresAt time.Time) error
+	UseRefreshToken(ctx context.Context, tokenHash string) (int, string, error)
+	RevokeRefreshTokenFamily(ctx context.Context, userID int, tokenHash string) error
+	RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+	Ping(ctx context.Context) error
+	SchemaVersion(ctx context.Context) (int, error)
+	Close() error
 }
 type StoragePostgresql struct {
 	Database *sql.DB
@@ -33,72 +103,253 @@ func New(ctx context.Context, port string, username string, host string, DBname
 	}
 	err = db.Ping()
 	if err != nil {
-		log.Println("bad connection:%v", err)
+		log.Printf("bad connection:%v", err)
 		return nil
 
 	}
 
 	return &StoragePostgresql{Database: db}
 }
-func (db *StoragePostgresql) RegisterUser(ctx context.Context, login, password string) (int, error) {
+
+// Close закрывает пул соединений с БД
+func (db *StoragePostgresql) Close() error {
+	return db.Database.Close()
+}
+
+// Ping проверяет соединение с БД
+func (db *StoragePostgresql) Ping(ctx context.Context) error {
+	return db.Database.PingContext(ctx)
+}
+
+// SchemaVersion возвращает номер последней примененной миграции
+func (db *StoragePostgresql) SchemaVersion(ctx context.Context) (int, error) {
+	var version int
+	query := "SELECT COALESCE(MAX(version), 0) FROM schema_migrations"
+	if err := db.Database.QueryRowContext(ctx, query).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to get schema version: %v", err)
+	}
+	return version, nil
+}
+
+func (db *StoragePostgresql) RegisterUser(ctx context.Context, login, passwordHash string) (int, error) {
 	var userID int
 	query := "INSERT INTO users (login, password) VALUES ($1, $2) RETURNING id"
-	err := db.Database.QueryRowContext(ctx, query, login, password).Scan(&userID)
+	err := db.Database.QueryRowContext(ctx, query, login, passwordHash).Scan(&userID)
 	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return 0, ErrLoginExists
+		}
 		return 0, fmt.Errorf("failed to register user: %v", err)
 	}
 	return userID, nil
 }
-func (db *StoragePostgresql) CheckUser(ctx context.Context, login, password string) (int, error) {
-	var userID int
-	query := "SELECT id FROM users WHERE login = $1 AND password = $2"
-	err := db.Database.QueryRowContext(ctx, query, login, password).Scan(&userID)
+
+// GetUserByLogin возвращает пользователя вместе с сохраненным хешем пароля (поле Password)
+func (db *StoragePostgresql) GetUserByLogin(ctx context.Context, login string) (user.User, error) {
+	query := "SELECT id, login, password, role, created_at, banned_at FROM users WHERE login = $1"
+	u, err := scanUser(db.Database.QueryRowContext(ctx, query, login), true)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return 0, fmt.Errorf("invalid login or password")
+			return user.User{}, ErrUserNotFound
 		}
-		return 0, fmt.Errorf("failed to check user: %v", err)
+		return user.User{}, fmt.Errorf("failed to get user: %v", err)
 	}
-	return userID, nil
+	return u, nil
 }
 
-func (db *StoragePostgresql) CreateAd(ctx context.Context, userID int, title, description, imageURL string, price float64) (int, error) {
+// GetUserByID возвращает пользователя по ID (без хеша пароля)
+func (db *StoragePostgresql) GetUserByID(ctx context.Context, userID int) (user.User, error) {
+	query := "SELECT id, login, role, created_at, banned_at FROM users WHERE id = $1"
+	u, err := scanUser(db.Database.QueryRowContext(ctx, query, userID), false)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return user.User{}, ErrUserNotFound
+		}
+		return user.User{}, fmt.Errorf("failed to get user: %v", err)
+	}
+	return u, nil
+}
+
+// ListUsers возвращает страницу пользователей по возрастанию ID и их общее число
+func (db *StoragePostgresql) ListUsers(ctx context.Context, limit, offset int) ([]user.User, int, error) {
+	var total int
+	if err := db.Database.QueryRowContext(ctx, "SELECT COUNT(*) FROM users").Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %v", err)
+	}
+	query := "SELECT id, login, role, created_at, banned_at FROM users ORDER BY id LIMIT $1 OFFSET $2"
+	rows, err := db.Database.QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list users: %v", err)
+	}
+	defer rows.Close()
+
+	var users []user.User
+	for rows.Next() {
+		u, err := scanUser(rows, false)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan user: %v", err)
+		}
+		users = append(users, u)
+	}
+	return users, total, nil
+}
+
+// SetUserRole меняет роль пользователя
+func (db *StoragePostgresql) SetUserRole(ctx context.Context, userID int, role string) error {
+	result, err := db.Database.ExecContext(ctx, "UPDATE users SET role = $1 WHERE id = $2", role, userID)
+	if err != nil {
+		return fmt.Errorf("failed to set user role: %v", err)
+	}
+	return userAffected(result)
+}
+
+// SetUserBanned блокирует или разблокирует пользователя; при блокировке отзываются все его refresh-токены
+func (db *StoragePostgresql) SetUserBanned(ctx context.Context, userID int, banned bool) error {
+	tx, err := db.Database.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := "UPDATE users SET banned_at = NULL WHERE id = $1"
+	if banned {
+		query = "UPDATE users SET banned_at = COALESCE(banned_at, CURRENT_TIMESTAMP) WHERE id = $1"
+	}
+	result, err := tx.ExecContext(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("failed to ban user: %v", err)
+	}
+	if err := userAffected(result); err != nil {
+		return err
+	}
+	if banned {
+		query = "UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND revoked_at IS NULL"
+		if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+			return fmt.Errorf("failed to revoke refresh tokens: %v", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanUser читает пользователя из строки с колонками id, login, [password,] role, created_at, banned_at
+func scanUser(row rowScanner, withPassword bool) (user.User, error) {
+	var u user.User
+	var createdAt, bannedAt sql.NullTime
+	dest := []interface{}{&u.ID, &u.Login}
+	if withPassword {
+		dest = append(dest, &u.Password)
+	}
+	dest = append(dest, &u.Role, &createdAt, &bannedAt)
+	if err := row.Scan(dest...); err != nil {
+		return user.User{}, err
+	}
+	u.CreatedAt = createdAt.Time
+	if bannedAt.Valid {
+		u.BannedAt = &bannedAt.Time
+	}
+	return u, nil
+}
+
+func userAffected(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check affected rows: %v", err)
+	}
+	if n == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// UpdatePasswordHash заменяет сохраненный хеш пароля
+func (db *StoragePostgresql) UpdatePasswordHash(ctx context.Context, userID int, passwordHash string) error {
+	query := "UPDATE users SET password = $1 WHERE id = $2"
+	if _, err := db.Database.ExecContext(ctx, query, passwordHash, userID); err != nil {
+		return fmt.Errorf("failed to update password hash: %v", err)
+	}
+	return nil
+}
+
+// CreateAd создает объявление вместе с галереей; первое изображение становится обложкой
+func (db *StoragePostgresql) CreateAd(ctx context.Context, userID int, title, description string, categoryID int, images []string, price float64, status string) (int, error) {
+	tx, err := db.Database.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
 	var adID int
-	query := "INSERT INTO ads (title, description, image_url, price, user_id) VALUES ($1, $2, $3, $4, $5) RETURNING id"
-	err := db.Database.QueryRowContext(ctx, query, title, description, imageURL, price, userID).Scan(&adID)
+	query := "INSERT INTO ads (title, description, image_url, price, user_id, status, category_id) VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, 0)) RETURNING id"
+	err = tx.QueryRowContext(ctx, query, title, description, coverURL(images), price, userID, status, categoryID).Scan(&adID)
 	if err != nil {
 		if strings.Contains(err.Error(), "foreign key") {
 			return 0, fmt.Errorf("user with ID %d does not exist", userID)
 		}
 		return 0, fmt.Errorf("failed to create ad: %v", err)
 	}
+	if _, err := insertAdImages(ctx, tx, adID, images); err != nil {
+		return 0, err
+	}
+	if err := insertPricePoint(ctx, tx, adID, price); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %v", err)
+	}
 	return adID, nil
 }
-func (db *StoragePostgresql) GetAds(ctx context.Context, page, pageSize int, sortBy, sortOrder string, minPrice, maxPrice float64) ([]models.Ad, error) {
-	// Валидация параметров
-	if page < 1 {
-		page = 1
-	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 10
+
+// adsFeedFrom — общий источник строк для GetAds и CountAds: total должен считаться по тем же строкам, что и страница
+const adsFeedFrom = "FROM ads a JOIN users u ON a.user_id = u.id"
+
+func (db *StoragePostgresql) GetAds(ctx context.Context, filter models.AdsFilter, viewer models.Viewer) ([]models.Ad, error) {
+	filter = filter.Normalize()
+	where, args, rank := buildAdsWhere(filter, viewer)
+
+	// при переходе назад (prev) выбираем в обратном порядке и разворачиваем результат
+	order := filter.SortOrder
+	if filter.Cursor != nil && filter.Cursor.Backward {
+		order = reverseOrder(order)
 	}
-	if sortBy != "created_at" && sortBy != "price" {
-		sortBy = "created_at"
+	orderBy := fmt.Sprintf("a.%s %s, a.id %s", filter.SortBy, order, order)
+	if filter.SortBy == models.SortByRelevance {
+		orderBy = fmt.Sprintf("%s %s, a.id %s", rank, order, order)
 	}
-	if sortOrder != "ASC" && sortOrder != "DESC" {
-		sortOrder = "DESC"
+
+	offset := (filter.Page - 1) * filter.PageSize
+	if filter.Cursor != nil {
+		keyset, keysetArgs, err := buildKeysetCondition(filter, len(args))
+		if err != nil {
+			return nil, err
+		}
+		where += " AND " + keyset
+		args = append(args, keysetArgs...)
+		offset = 0
 	}
 
-	offset := (page - 1) * pageSize
+	// персональные поля считаются в том же запросе; для анонимного viewer.UserID = 0 не совпадет ни с одним автором
+	args = append(args, viewer.UserID)
+	viewerArg := len(args)
+	args = append(args, filter.PageSize, offset)
 	query := fmt.Sprintf(`
-        SELECT a.id, a.title, a.description, a.image_url, a.price, a.user_id, u.login, a.created_at
-        FROM ads a
-        JOIN users u ON a.user_id = u.id
-        WHERE a.price BETWEEN $1 AND $2
-        ORDER BY a.%s %s
-        LIMIT $3 OFFSET $4`, sortBy, sortOrder)
+        SELECT a.id, a.title, a.description, a.image_url, a.price, a.user_id, u.login, a.created_at,
+               a.status, COALESCE(a.rejection_reason, ''), COALESCE(a.category_id, 0), a.user_id = $%d AS is_owner,
+               (SELECT COUNT(*) FROM favorites f WHERE f.ad_id = a.id) AS favorites_count,
+               EXISTS (SELECT 1 FROM favorites f WHERE f.ad_id = a.id AND f.user_id = $%d) AS is_favorite
+        %s
+        WHERE %s
+        ORDER BY %s
+        LIMIT $%d OFFSET $%d`, viewerArg, viewerArg, adsFeedFrom, where, orderBy, len(args)-1, len(args))
 
-	rows, err := db.Database.QueryContext(ctx, query, minPrice, maxPrice, pageSize, offset)
+	rows, err := db.Database.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get ads: %v", err)
 	}
@@ -108,22 +359,1001 @@ func (db *StoragePostgresql) GetAds(ctx context.Context, page, pageSize int, sor
 	for rows.Next() {
 		var ad models.Ad
 		var createdAt time.Time
-		if err := rows.Scan(&ad.ID, &ad.Title, &ad.Description, &ad.ImageURL, &ad.Price, &ad.UserID, &ad.Login, &createdAt); err != nil {
+		if err := rows.Scan(&ad.ID, &ad.Title, &ad.Description, &ad.ImageURL, &ad.Price, &ad.UserID, &ad.Login, &createdAt, &ad.Status, &ad.RejectionReason, &ad.CategoryID, &ad.IsOwner,
+			&ad.FavoritesCount, &ad.IsFavorite); err != nil {
 			return nil, fmt.Errorf("failed to scan ad: %v", err)
 		}
 		ad.CreatedAt = createdAt.Format(time.RFC3339)
+		ad.CreatedAtTime = createdAt
 		ads = append(ads, ad)
 	}
+	if filter.Cursor != nil && filter.Cursor.Backward {
+		reverseAds(ads)
+	}
 	return ads, nil
 }
 
-// IsAdOwner проверяет, является ли пользователь владельцем объявления
-func (db *StoragePostgresql) IsAdOwner(ctx context.Context, adID, userID int) (bool, error) {
+// CountAds возвращает число объявлений, подходящих под фильтр (без учета пагинации)
+func (db *StoragePostgresql) CountAds(ctx context.Context, filter models.AdsFilter, viewer models.Viewer) (int, error) {
+	where, args, _ := buildAdsWhere(filter.Normalize(), viewer)
+	query := fmt.Sprintf("SELECT COUNT(*) %s WHERE %s", adsFeedFrom, where)
+	var total int
+	if err := db.Database.QueryRowContext(ctx, query, args...).Scan(&total); err != nil {
+		return 0, fmt.Errorf("failed to count ads: %v", err)
+	}
+	return total, nil
+}
+
+// buildKeysetCondition строит условие "строго после курсора" по паре (ключ сортировки, id).
+// argOffset — число уже занятых плейсхолдеров.
+func buildKeysetCondition(filter models.AdsFilter, argOffset int) (string, []interface{}, error) {
+	c := filter.Cursor
+	op := ">"
+	if (filter.SortOrder == "DESC") != c.Backward {
+		op = "<"
+	}
+	switch filter.SortBy {
+	case models.SortByCreatedAt:
+		createdAt, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid cursor value: %v", err)
+		}
+		return fmt.Sprintf("(a.created_at, a.id) %s ($%d, $%d)", op, argOffset+1, argOffset+2), []interface{}{createdAt, c.ID}, nil
+	case models.SortByPrice:
+		// цена передается строкой и сравнивается как numeric, чтобы не терять точность на float
+		return fmt.Sprintf("(a.price, a.id) %s ($%d::numeric, $%d)", op, argOffset+1, argOffset+2), []interface{}{c.Value, c.ID}, nil
+	default:
+		return "", nil, fmt.Errorf("cursor pagination is not supported for sort_by=%s", filter.SortBy)
+	}
+}
+
+func reverseOrder(order string) string {
+	if order == "ASC" {
+		return "DESC"
+	}
+	return "ASC"
+}
+
+func reverseAds(ads []models.Ad) {
+	for i, j := 0, len(ads)-1; i < j; i, j = i+1, j-1 {
+		ads[i], ads[j] = ads[j], ads[i]
+	}
+}
+
+// buildAdsWhere собирает условие WHERE и его аргументы для ленты объявлений.
+// rank — выражение релевантности полнотекстового поиска (пустое, если поиска нет).
+func buildAdsWhere(filter models.AdsFilter, viewer models.Viewer) (where string, args []interface{}, rank string) {
+	conds := []string{"a.price BETWEEN $1 AND $2"}
+	args = []interface{}{filter.MinPrice, filter.MaxPrice}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conds = append(conds, fmt.Sprintf("a.status = $%d", len(args)))
+	} else {
+		// автор видит свои объявления в любом статусе, остальные — только опубликованные
+		args = append(args, viewer.UserID)
+		conds = append(conds, fmt.Sprintf("(a.status = 'published' OR a.user_id = $%d)", len(args)))
+	}
+	if len(filter.CategoryIDs) > 0 {
+		args = append(args, filter.CategoryIDs)
+		conds = append(conds, fmt.Sprintf("a.category_id = ANY($%d)", len(args)))
+	}
+	if filter.FavoritesOf != 0 {
+		args = append(args, filter.FavoritesOf)
+		conds = append(conds, fmt.Sprintf("EXISTS (SELECT 1 FROM favorites f WHERE f.ad_id = a.id AND f.user_id = $%d)", len(args)))
+	}
+	if filter.AdID != 0 {
+		args = append(args, filter.AdID)
+		conds = append(conds, fmt.Sprintf("a.id = $%d", len(args)))
+	}
+	if filter.Query != "" {
+		args = append(args, filter.Query)
+		// объявления на русском и английском: совпадение по любой из конфигураций
+		tsQuery := fmt.Sprintf("(websearch_to_tsquery('russian', $%d) || websearch_to_tsquery('english', $%d))", len(args), len(args))
+		conds = append(conds, "a.search_vector @@ "+tsQuery)
+		rank = fmt.Sprintf("ts_rank(a.search_vector, %s)", tsQuery)
+	}
+	return strings.Join(conds, " AND "), args, rank
+}
+
+// GetAd возвращает объявление по ID
+func (db *StoragePostgresql) GetAd(ctx context.Context, adID int) (models.Ad, error) {
+	var ad models.Ad
+	var createdAt time.Time
+	query := `
+        SELECT a.id, a.title, a.description, a.image_url, a.price, a.user_id, u.login, a.created_at,
+               a.status, COALESCE(a.rejection_reason, ''), COALESCE(a.category_id, 0),
+               (SELECT COUNT(*) FROM favorites f WHERE f.ad_id = a.id)
+        FROM ads a
+        JOIN users u ON a.user_id = u.id
+        WHERE a.id = $1`
+	err := db.Database.QueryRowContext(ctx, query, adID).Scan(&ad.ID, &ad.Title, &ad.Description, &ad.ImageURL, &ad.Price, &ad.UserID, &ad.Login, &createdAt,
+		&ad.Status, &ad.RejectionReason, &ad.CategoryID, &ad.FavoritesCount)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Ad{}, ErrAdNotFound
+		}
+		return models.Ad{}, fmt.Errorf("failed to get ad: %v", err)
+	}
+	ad.CreatedAt = createdAt.Format(time.RFC3339)
+	ad.CreatedAtTime = createdAt
+
+	rows, err := db.Database.QueryContext(ctx, "SELECT id, url, position FROM ad_images WHERE ad_id = $1 ORDER BY position", adID)
+	if err != nil {
+		return models.Ad{}, fmt.Errorf("failed to get ad images: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var img models.AdImage
+		if err := rows.Scan(&img.ID, &img.URL, &img.Position); err != nil {
+			return models.Ad{}, fmt.Errorf("failed to scan ad image: %v", err)
+		}
+		ad.Images = append(ad.Images, img)
+	}
+	if err := rows.Err(); err != nil {
+		return models.Ad{}, fmt.Errorf("failed to get ad images: %v", err)
+	}
+	return ad, nil
+}
+
+// UpdateAd сохраняет изменяемые поля объявления вместе с категорией.
+// Непустой images заменяет галерею в той же транзакции; nil оставляет ее как есть.
+// Статус читается под блокировкой строки: при contentChanged опубликованное или отклоненное объявление
+// уходит на проверку, иначе статус и решение модератора сохраняются. Архивное объявление не меняется
+func (db *StoragePostgresql) UpdateAd(ctx context.Context, ad models.Ad, images []string, contentChanged bool) (models.AdUpdateResult, error) {
+	tx, err := db.Database.BeginTx(ctx, nil)
+	if err != nil {
+		return models.AdUpdateResult{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	// цена и статус читаются под блокировкой строки, чтобы правка не потеряла запись истории цены
+	// и не затерла решение модератора, принятое после чтения объявления сервисом
+	var res models.AdUpdateResult
+	if err := tx.QueryRowContext(ctx, "SELECT price, status FROM ads WHERE id = $1 FOR UPDATE", ad.ID).Scan(&res.OldPrice, &res.PrevStatus); err != nil {
+		if err == sql.ErrNoRows {
+			return models.AdUpdateResult{}, ErrAdNotFound
+		}
+		return models.AdUpdateResult{}, fmt.Errorf("failed to update ad: %v", err)
+	}
+	if res.PrevStatus == models.AdStatusArchived {
+		return models.AdUpdateResult{}, ErrAdStatusConflict
+	}
+	res.Status = res.PrevStatus
+	if contentChanged {
+		res.Status = models.StatusAfterContentChange(res.PrevStatus)
+	}
+	// причина отклонения остается, только если статус не изменился
+	query := `
+        UPDATE ads SET title = $1, description = $2, image_url = $3, price = $4, status = $5,
+               rejection_reason = CASE WHEN status = $5 THEN rejection_reason END, category_id = NULLIF($7, 0)
+        WHERE id = $6
+        RETURNING COALESCE(rejection_reason, '')`
+	err = tx.QueryRowContext(ctx, query, ad.Title, ad.Description, ad.ImageURL, ad.Price, res.Status, ad.ID, ad.CategoryID).Scan(&res.RejectionReason)
+	if err != nil {
+		return models.AdUpdateResult{}, fmt.Errorf("failed to update ad: %v", err)
+	}
+	if ad.Price != res.OldPrice {
+		if err := insertPricePoint(ctx, tx, ad.ID, ad.Price); err != nil {
+			return models.AdUpdateResult{}, err
+		}
+	}
+	if images != nil {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM ad_images WHERE ad_id = $1", ad.ID); err != nil {
+			return models.AdUpdateResult{}, fmt.Errorf("failed to delete ad images: %v", err)
+		}
+		if err := syncAdCover(ctx, tx, ad.ID, coverURL(images)); err != nil {
+			return models.AdUpdateResult{}, err
+		}
+		if res.Images, err = insertAdImages(ctx, tx, ad.ID, images); err != nil {
+			return models.AdUpdateResult{}, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return models.AdUpdateResult{}, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return res, nil
+}
+
+// TransitionAd переводит объявление в статус to, если текущий статус входит в from.
+// reason сохраняется как причина отклонения; moderatorID != 0 фиксирует решение модератора
+func (db *StoragePostgresql) TransitionAd(ctx context.Context, adID int, from []string, to, reason string, moderatorID int) error {
+	query := "UPDATE ads SET status = $1, rejection_reason = NULLIF($2, '') WHERE id = $3 AND status = ANY($4)"
+	args := []interface{}{to, reason, adID, from}
+	if moderatorID != 0 {
+		query = "UPDATE ads SET status = $1, rejection_reason = NULLIF($2, ''), moderated_by = $5, moderated_at = CURRENT_TIMESTAMP WHERE id = $3 AND status = ANY($4)"
+		args = append(args, moderatorID)
+	}
+	res, err := db.Database.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to change ad status: %v", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		var exists bool
+		if err := db.Database.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM ads WHERE id = $1)", adID).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to check ad: %v", err)
+		}
+		if !exists {
+			return ErrAdNotFound
+		}
+		return ErrAdStatusConflict
+	}
+	return nil
+}
+
+// DeleteAd удаляет объявление
+func (db *StoragePostgresql) DeleteAd(ctx context.Context, adID int) error {
+	res, err := db.Database.ExecContext(ctx, "DELETE FROM ads WHERE id = $1", adID)
+	if err != nil {
+		return fmt.Errorf("failed to delete ad: %v", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrAdNotFound
+	}
+	return nil
+}
+
+// AddAdImage добавляет изображение в конец галереи, если в ней меньше maxImages изображений.
+// Опубликованное или отклоненное объявление в той же транзакции уходит на повторную проверку
+func (db *StoragePostgresql) AddAdImage(ctx context.Context, adID int, url string, maxImages int) (models.AdImage, error) {
+	tx, err := db.Database.BeginTx(ctx, nil)
+	if err != nil {
+		return models.AdImage{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	// блокировка строки объявления сериализует параллельные добавления: иначе они получат одну позицию
+	// и вместе превысят лимит
+	status, err := lockEditableAd(ctx, tx, adID)
+	if err != nil {
+		return models.AdImage{}, err
+	}
+	var count int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM ad_images WHERE ad_id = $1", adID).Scan(&count); err != nil {
+		return models.AdImage{}, fmt.Errorf("failed to count ad images: %v", err)
+	}
+	if count >= maxImages {
+		return models.AdImage{}, ErrAdImageLimit
+	}
+	img := models.AdImage{URL: url}
+	query := `
+        INSERT INTO ad_images (ad_id, url, position)
+        SELECT $1, $2, COALESCE(MAX(position) + 1, 0) FROM ad_images WHERE ad_id = $1
+        RETURNING id, position`
+	if err := tx.QueryRowContext(ctx, query, adID, url).Scan(&img.ID, &img.Position); err != nil {
+		return models.AdImage{}, fmt.Errorf("failed to add ad image: %v", err)
+	}
+	if img.Position == 0 {
+		if err := syncAdCover(ctx, tx, adID, url); err != nil {
+			return models.AdImage{}, err
+		}
+	}
+	if err := requeueAfterContentChange(ctx, tx, adID, status); err != nil {
+		return models.AdImage{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.AdImage{}, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return img, nil
+}
+
+// DeleteAdImage удаляет изображение из галереи и сдвигает следующие за ним.
+// Опубликованное или отклоненное объявление в той же транзакции уходит на повторную проверку
+func (db *StoragePostgresql) DeleteAdImage(ctx context.Context, adID, imageID int) error {
+	tx, err := db.Database.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	status, err := lockEditableAd(ctx, tx, adID)
+	if err != nil {
+		return err
+	}
+	var position int
+	err = tx.QueryRowContext(ctx, "DELETE FROM ad_images WHERE id = $1 AND ad_id = $2 RETURNING position", imageID, adID).Scan(&position)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrAdImageNotFound
+		}
+		return fmt.Errorf("failed to delete ad image: %v", err)
+	}
+	query := "UPDATE ad_images SET position = position - 1 WHERE ad_id = $1 AND position > $2"
+	if _, err := tx.ExecContext(ctx, query, adID, position); err != nil {
+		return fmt.Errorf("failed to shift ad images: %v", err)
+	}
+	if err := syncAdCoverFromImages(ctx, tx, adID); err != nil {
+		return err
+	}
+	if err := requeueAfterContentChange(ctx, tx, adID, status); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+// ReorderAdImages расставляет изображения в порядке imageIDs; imageIDs должен содержать всю галерею.
+// Если порядок изменился, опубликованное или отклоненное объявление уходит на повторную проверку
+func (db *StoragePostgresql) ReorderAdImages(ctx context.Context, adID int, imageIDs []int) error {
+	tx, err := db.Database.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	status, err := lockEditableAd(ctx, tx, adID)
+	if err != nil {
+		return err
+	}
+	current, err := adImageIDs(ctx, tx, adID)
+	if err != nil {
+		return err
+	}
+	if slices.Equal(current, imageIDs) {
+		return nil
+	}
+	// уникальность (ad_id, position) проверяется при коммите, поэтому позиции можно менять одним запросом
+	query := "UPDATE ad_images SET position = array_position($2::int[], id) - 1 WHERE ad_id = $1 AND id = ANY($2)"
+	result, err := tx.ExecContext(ctx, query, adID, imageIDs)
+	if err != nil {
+		return fmt.Errorf("failed to reorder ad images: %v", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && int(n) != len(imageIDs) {
+		return ErrAdImageNotFound
+	}
+	if err := syncAdCoverFromImages(ctx, tx, adID); err != nil {
+		return err
+	}
+	if err := requeueAfterContentChange(ctx, tx, adID, status); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+// GetCategories возвращает все категории плоским списком в порядке показа
+func (db *StoragePostgresql) GetCategories(ctx context.Context) ([]models.Category, error) {
+	rows, err := db.Database.QueryContext(ctx, "SELECT id, COALESCE(parent_id, 0), name, slug FROM categories ORDER BY position, id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get categories: %v", err)
+	}
+	defer rows.Close()
+	var categories []models.Category
+	for rows.Next() {
+		var c models.Category
+		if err := rows.Scan(&c.ID, &c.ParentID, &c.Name, &c.Slug); err != nil {
+			return nil, fmt.Errorf("failed to scan category: %v", err)
+		}
+		categories = append(categories, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get categories: %v", err)
+	}
+	return categories, nil
+}
+
+// AddFavorite добавляет объявление в избранное и запоминает текущую цену как увиденную.
+// Повторное добавление обновляет увиденную цену и порог; nil priceThreshold убирает порог
+func (db *StoragePostgresql) AddFavorite(ctx context.Context, userID, adID int, priceThreshold *float64) error {
+	query := `
+        INSERT INTO favorites (user_id, ad_id, last_seen_price, price_threshold)
+        SELECT $1, id, price, $3 FROM ads WHERE id = $2
+        ON CONFLICT (user_id, ad_id) DO UPDATE
+        SET last_seen_price = EXCLUDED.last_seen_price, price_threshold = EXCLUDED.price_threshold`
+	res, err := db.Database.ExecContext(ctx, query, userID, adID, priceThreshold)
+	if err != nil {
+		return fmt.Errorf("failed to add favorite: %v", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrAdNotFound
+	}
+	return nil
+}
+
+// RemoveFavorite убирает объявление из избранного; отсутствие записи не ошибка
+func (db *StoragePostgresql) RemoveFavorite(ctx context.Context, userID, adID int) error {
+	if _, err := db.Database.ExecContext(ctx, "DELETE FROM favorites WHERE user_id = $1 AND ad_id = $2", userID, adID); err != nil {
+		return fmt.Errorf("failed to remove favorite: %v", err)
+	}
+	return nil
+}
+
+// IsFavorite сообщает, добавил ли пользователь объявление в избранное
+func (db *StoragePostgresql) IsFavorite(ctx context.Context, userID, adID int) (bool, error) {
 	var exists bool
-	query := "SELECT EXISTS (SELECT 1 FROM ads WHERE id = $1 AND user_id = $2)"
-	err := db.Database.QueryRowContext(ctx, query, adID, userID).Scan(&exists)
+	query := "SELECT EXISTS (SELECT 1 FROM favorites WHERE user_id = $1 AND ad_id = $2)"
+	if err := db.Database.QueryRowContext(ctx, query, userID, adID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check favorite: %v", err)
+	}
+	return exists, nil
+}
+
+// ClaimPriceDropWatchers возвращает пользователей, для которых новая цена — снижение: она ниже увиденной
+// и, если задан порог, не выше его, вместе с прежней увиденной ценой.
+// Для них price сразу запоминается как увиденная, чтобы не уведомлять повторно
+func (db *StoragePostgresql) ClaimPriceDropWatchers(ctx context.Context, adID int, price float64) ([]models.PriceWatcher, error) {
+	// RETURNING видит только новые значения f, поэтому прежняя цена берется из той же строки через old
+	query := `
+        UPDATE favorites f SET last_seen_price = $2
+        FROM favorites old
+        WHERE old.user_id = f.user_id AND old.ad_id = f.ad_id
+          AND f.ad_id = $1 AND $2 < f.last_seen_price AND (f.price_threshold IS NULL OR $2 <= f.price_threshold)
+        RETURNING f.user_id, old.last_seen_price`
+	rows, err := db.Database.QueryContext(ctx, query, adID, price)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find price drop watchers: %v", err)
+	}
+	defer rows.Close()
+	var watchers []models.PriceWatcher
+	for rows.Next() {
+		var w models.PriceWatcher
+		if err := rows.Scan(&w.UserID, &w.LastSeenPrice); err != nil {
+			return nil, fmt.Errorf("failed to scan price drop watcher: %v", err)
+		}
+		watchers = append(watchers, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find price drop watchers: %v", err)
+	}
+	return watchers, nil
+}
+
+// GetPriceHistory возвращает историю цены объявления от старых записей к новым
+func (db *StoragePostgresql) GetPriceHistory(ctx context.Context, adID int) ([]models.PricePoint, error) {
+	rows, err := db.Database.QueryContext(ctx, "SELECT price, changed_at FROM ad_price_history WHERE ad_id = $1 ORDER BY id", adID)
 	if err != nil {
-		return false, fmt.Errorf("failed to check ad owner: %v", err)
+		return nil, fmt.Errorf("failed to get price history: %v", err)
+	}
+	defer rows.Close()
+	var history []models.PricePoint
+	for rows.Next() {
+		var p models.PricePoint
+		var changedAt time.Time
+		if err := rows.Scan(&p.Price, &changedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan price history: %v", err)
+		}
+		p.ChangedAt = changedAt.Format(time.RFC3339)
+		history = append(history, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get price history: %v", err)
+	}
+	return history, nil
+}
+
+// threadSelect выбирает ветки с последним сообщением и числом непрочитанных для пользователя $1
+const threadSelect = `
+        SELECT t.id, t.ad_id, a.title, t.buyer_id, b.login, t.seller_id, s.login, t.created_at,
+               m.id, m.sender_id, m.body, m.created_at,
+               (SELECT COUNT(*) FROM messages um
+                WHERE um.thread_id = t.id AND um.sender_id <> $1
+                  AND um.id > CASE WHEN t.buyer_id = $1 THEN t.buyer_last_read_id ELSE t.seller_last_read_id END)
+        FROM threads t
+        JOIN ads a ON a.id = t.ad_id
+        JOIN users b ON b.id = t.buyer_id
+        JOIN users s ON s.id = t.seller_id
+        LEFT JOIN LATERAL (
+            SELECT id, sender_id, body, created_at FROM messages WHERE thread_id = t.id ORDER BY id DESC LIMIT 1
+        ) m ON true`
+
+// GetOrCreateThread возвращает ветку покупателя по объявлению, создавая ее при первом обращении
+func (db *StoragePostgresql) GetOrCreateThread(ctx context.Context, adID, buyerID, sellerID int) (int, error) {
+	// DO UPDATE вместо DO NOTHING, чтобы RETURNING вернул ID уже существующей ветки
+	query := `
+        INSERT INTO threads (ad_id, buyer_id, seller_id) VALUES ($1, $2, $3)
+        ON CONFLICT (ad_id, buyer_id) DO UPDATE SET ad_id = EXCLUDED.ad_id
+        RETURNING id`
+	var threadID int
+	if err := db.Database.QueryRowContext(ctx, query, adID, buyerID, sellerID).Scan(&threadID); err != nil {
+		return 0, fmt.Errorf("failed to create thread: %v", err)
+	}
+	return threadID, nil
+}
+
+// GetThread возвращает ветку; непрочитанные считаются для userID
+func (db *StoragePostgresql) GetThread(ctx context.Context, threadID, userID int) (models.Thread, error) {
+	t, err := scanThread(db.Database.QueryRowContext(ctx, threadSelect+" WHERE t.id = $2", userID, threadID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Thread{}, ErrThreadNotFound
+		}
+		return models.Thread{}, fmt.Errorf("failed to get thread: %v", err)
+	}
+	return t, nil
+}
+
+// ListThreads возвращает ветки пользователя (как покупателя и как продавца), свежие первыми, и их общее число
+func (db *StoragePostgresql) ListThreads(ctx context.Context, userID, limit, offset int) ([]models.Thread, int, error) {
+	var total int
+	if err := db.Database.QueryRowContext(ctx, "SELECT COUNT(*) FROM threads WHERE buyer_id = $1 OR seller_id = $1", userID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count threads: %v", err)
+	}
+	query := threadSelect + `
+        WHERE t.buyer_id = $1 OR t.seller_id = $1
+        ORDER BY t.last_message_at DESC, t.id DESC
+        LIMIT $2 OFFSET $3`
+	rows, err := db.Database.QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list threads: %v", err)
+	}
+	defer rows.Close()
+	var threads []models.Thread
+	for rows.Next() {
+		t, err := scanThread(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan thread: %v", err)
+		}
+		threads = append(threads, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list threads: %v", err)
+	}
+	return threads, total, nil
+}
+
+// AddMessage сохраняет сообщение; своя ветка у отправителя считается прочитанной
+func (db *StoragePostgresql) AddMessage(ctx context.Context, threadID, senderID int, body string) (models.Message, error) {
+	tx, err := db.Database.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Message{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	msg := models.Message{ThreadID: threadID, SenderID: senderID, Body: body}
+	var createdAt time.Time
+	query := "INSERT INTO messages (thread_id, sender_id, body) VALUES ($1, $2, $3) RETURNING id, created_at"
+	if err := tx.QueryRowContext(ctx, query, threadID, senderID, body).Scan(&msg.ID, &createdAt); err != nil {
+		return models.Message{}, fmt.Errorf("failed to add message: %v", err)
+	}
+	query = `
+        UPDATE threads SET last_message_at = $3,
+            buyer_last_read_id = CASE WHEN buyer_id = $2 THEN $4 ELSE buyer_last_read_id END,
+            seller_last_read_id = CASE WHEN seller_id = $2 THEN $4 ELSE seller_last_read_id END
+        WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, query, threadID, senderID, createdAt, msg.ID); err != nil {
+		return models.Message{}, fmt.Errorf("failed to update thread: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Message{}, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	msg.CreatedAt = createdAt.Format(time.RFC3339)
+	return msg, nil
+}
+
+// GetMessages возвращает сообщения ветки от старых к новым и их общее число
+func (db *StoragePostgresql) GetMessages(ctx context.Context, threadID, limit, offset int) ([]models.Message, int, error) {
+	var total int
+	if err := db.Database.QueryRowContext(ctx, "SELECT COUNT(*) FROM messages WHERE thread_id = $1", threadID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count messages: %v", err)
+	}
+	query := "SELECT id, thread_id, sender_id, body, created_at FROM messages WHERE thread_id = $1 ORDER BY id LIMIT $2 OFFSET $3"
+	rows, err := db.Database.QueryContext(ctx, query, threadID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get messages: %v", err)
+	}
+	defer rows.Close()
+	var messages []models.Message
+	for rows.Next() {
+		var msg models.Message
+		var createdAt time.Time
+		if err := rows.Scan(&msg.ID, &msg.ThreadID, &msg.SenderID, &msg.Body, &createdAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan message: %v", err)
+		}
+		msg.CreatedAt = createdAt.Format(time.RFC3339)
+		messages = append(messages, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to get messages: %v", err)
+	}
+	return messages, total, nil
+}
+
+// MarkThreadRead отмечает все сообщения ветки прочитанными для userID
+func (db *StoragePostgresql) MarkThreadRead(ctx context.Context, threadID, userID int) error {
+	query := `
+        UPDATE threads SET
+            buyer_last_read_id = CASE WHEN buyer_id = $2 THEN last.id ELSE buyer_last_read_id END,
+            seller_last_read_id = CASE WHEN seller_id = $2 THEN last.id ELSE seller_last_read_id END
+        FROM (SELECT COALESCE(MAX(id), 0) AS id FROM messages WHERE thread_id = $1) last
+        WHERE threads.id = $1`
+	result, err := db.Database.ExecContext(ctx, query, threadID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to mark thread as read: %v", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrThreadNotFound
+	}
+	return nil
+}
+
+// scanThread читает строку threadSelect
+func scanThread(row rowScanner) (models.Thread, error) {
+	var t models.Thread
+	var createdAt time.Time
+	var msgID, msgSenderID sql.NullInt64
+	var msgBody sql.NullString
+	var msgCreatedAt sql.NullTime
+	err := row.Scan(&t.ID, &t.AdID, &t.AdTitle, &t.BuyerID, &t.BuyerLogin, &t.SellerID, &t.SellerLogin, &createdAt,
+		&msgID, &msgSenderID, &msgBody, &msgCreatedAt, &t.UnreadCount)
+	if err != nil {
+		return models.Thread{}, err
+	}
+	t.CreatedAt = createdAt.Format(time.RFC3339)
+	if msgID.Valid {
+		t.LastMessage = &models.Message{
+			ID:        int(msgID.Int64),
+			ThreadID:  t.ID,
+			SenderID:  int(msgSenderID.Int64),
+			Body:      msgBody.String,
+			CreatedAt: msgCreatedAt.Time.Format(time.RFC3339),
+		}
+	}
+	return t, nil
+}
+
+// CreateSavedSearch сохраняет поиск пользователя, если у него меньше maxSearches поисков
+func (db *StoragePostgresql) CreateSavedSearch(ctx context.Context, search models.SavedSearch, maxSearches int) (models.SavedSearch, error) {
+	tx, err := db.Database.BeginTx(ctx, nil)
+	if err != nil {
+		return models.SavedSearch{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	// блокировка строки пользователя сериализует параллельные сохранения, иначе они вместе превысят лимит
+	var userID int
+	if err := tx.QueryRowContext(ctx, "SELECT id FROM users WHERE id = $1 FOR UPDATE", search.UserID).Scan(&userID); err != nil {
+		if err == sql.ErrNoRows {
+			return models.SavedSearch{}, ErrUserNotFound
+		}
+		return models.SavedSearch{}, fmt.Errorf("failed to lock user: %v", err)
+	}
+	var count int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM saved_searches WHERE user_id = $1", search.UserID).Scan(&count); err != nil {
+		return models.SavedSearch{}, fmt.Errorf("failed to count saved searches: %v", err)
+	}
+	if count >= maxSearches {
+		return models.SavedSearch{}, ErrSavedSearchLimit
+	}
+
+	var createdAt time.Time
+	query := `
+        INSERT INTO saved_searches (user_id, name, query, min_price, max_price, category_id, sort_by, sort_order)
+        VALUES ($1, $2, $3, $4, $5, NULLIF($6, 0), $7, $8)
+        RETURNING id, created_at`
+	err = tx.QueryRowContext(ctx, query, search.UserID, search.Name, search.Query, search.MinPrice, search.MaxPrice,
+		search.Category, search.SortBy, search.SortOrder).Scan(&search.ID, &createdAt)
+	if err != nil {
+		return models.SavedSearch{}, fmt.Errorf("failed to create saved search: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return models.SavedSearch{}, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	search.CreatedAt = createdAt.Format(time.RFC3339)
+	return search, nil
+}
+
+// ListSavedSearches возвращает поиски пользователя в порядке создания
+func (db *StoragePostgresql) ListSavedSearches(ctx context.Context, userID int) ([]models.SavedSearch, error) {
+	return db.querySavedSearches(ctx, savedSearchSelect+" WHERE user_id = $1 ORDER BY id", userID)
+}
+
+// DeleteSavedSearch удаляет поиск пользователя
+func (db *StoragePostgresql) DeleteSavedSearch(ctx context.Context, userID, searchID int) error {
+	result, err := db.Database.ExecContext(ctx, "DELETE FROM saved_searches WHERE id = $1 AND user_id = $2", searchID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete saved search: %v", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrSavedSearchNotFound
+	}
+	return nil
+}
+
+// SavedSearchesForPrice возвращает поиски всех пользователей, в диапазон цен которых попадает price
+func (db *StoragePostgresql) SavedSearchesForPrice(ctx context.Context, price float64) ([]models.SavedSearch, error) {
+	return db.querySavedSearches(ctx, savedSearchSelect+" WHERE $1 BETWEEN min_price AND max_price ORDER BY id", price)
+}
+
+// savedSearchSelect выбирает поля сохраненного поиска в порядке scan в querySavedSearches
+const savedSearchSelect = `
+        SELECT id, user_id, name, query, min_price, max_price, COALESCE(category_id, 0), sort_by, sort_order, created_at
+        FROM saved_searches`
+
+func (db *StoragePostgresql) querySavedSearches(ctx context.Context, query string, args ...interface{}) ([]models.SavedSearch, error) {
+	rows, err := db.Database.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list saved searches: %v", err)
+	}
+	defer rows.Close()
+	var searches []models.SavedSearch
+	for rows.Next() {
+		var s models.SavedSearch
+		var createdAt time.Time
+		if err := rows.Scan(&s.ID, &s.UserID, &s.Name, &s.Query, &s.MinPrice, &s.MaxPrice, &s.Category, &s.SortBy, &s.SortOrder, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan saved search: %v", err)
+		}
+		s.CreatedAt = createdAt.Format(time.RFC3339)
+		searches = append(searches, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list saved searches: %v", err)
+	}
+	return searches, nil
+}
+
+// AddNotification сохраняет уведомление. false — такое совпадение с сохраненным поиском уже было, уведомление не создано
+func (db *StoragePostgresql) AddNotification(ctx context.Context, n models.Notification) (models.Notification, bool, error) {
+	var createdAt time.Time
+	query := `
+        INSERT INTO notifications (user_id, type, ad_id, saved_search_id, old_price, new_price)
+        VALUES ($1, $2, $3, NULLIF($4, 0), $5, $6)
+        ON CONFLICT (saved_search_id, ad_id) WHERE saved_search_id IS NOT NULL DO NOTHING
+        RETURNING id, created_at`
+	err := db.Database.QueryRowContext(ctx, query, n.UserID, n.Type, n.AdID, n.SavedSearchID, n.OldPrice, n.NewPrice).Scan(&n.ID, &createdAt)
+	if err == sql.ErrNoRows {
+		return models.Notification{}, false, nil
+	}
+	if err != nil {
+		return models.Notification{}, false, fmt.Errorf("failed to add notification: %v", err)
+	}
+	if err := db.Database.QueryRowContext(ctx, "SELECT title FROM ads WHERE id = $1", n.AdID).Scan(&n.AdTitle); err != nil {
+		return models.Notification{}, false, fmt.Errorf("failed to get ad title: %v", err)
+	}
+	n.CreatedAt = createdAt.Format(time.RFC3339)
+	return n, true, nil
+}
+
+// ListNotifications возвращает уведомления пользователя, новые первыми, и их общее число
+func (db *StoragePostgresql) ListNotifications(ctx context.Context, userID int, unreadOnly bool, limit, offset int) ([]models.Notification, int, error) {
+	var total int
+	query := "SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND ($2 = FALSE OR read_at IS NULL)"
+	if err := db.Database.QueryRowContext(ctx, query, userID, unreadOnly).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count notifications: %v", err)
+	}
+	query = `
+        SELECT n.id, n.type, n.ad_id, a.title, COALESCE(n.saved_search_id, 0), n.old_price, n.new_price, n.read_at IS NOT NULL, n.created_at
+        FROM notifications n
+        JOIN ads a ON a.id = n.ad_id
+        WHERE n.user_id = $1 AND ($2 = FALSE OR n.read_at IS NULL)
+        ORDER BY n.id DESC
+        LIMIT $3 OFFSET $4`
+	rows, err := db.Database.QueryContext(ctx, query, userID, unreadOnly, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list notifications: %v", err)
+	}
+	defer rows.Close()
+	var notifications []models.Notification
+	for rows.Next() {
+		n := models.Notification{UserID: userID}
+		var createdAt time.Time
+		var oldPrice, newPrice sql.NullFloat64
+		if err := rows.Scan(&n.ID, &n.Type, &n.AdID, &n.AdTitle, &n.SavedSearchID, &oldPrice, &newPrice, &n.Read, &createdAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan notification: %v", err)
+		}
+		if oldPrice.Valid {
+			n.OldPrice = &oldPrice.Float64
+		}
+		if newPrice.Valid {
+			n.NewPrice = &newPrice.Float64
+		}
+		n.CreatedAt = createdAt.Format(time.RFC3339)
+		notifications = append(notifications, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list notifications: %v", err)
+	}
+	return notifications, total, nil
+}
+
+// CountUnreadNotifications возвращает число непрочитанных уведомлений пользователя
+func (db *StoragePostgresql) CountUnreadNotifications(ctx context.Context, userID int) (int, error) {
+	var unread int
+	query := "SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL"
+	if err := db.Database.QueryRowContext(ctx, query, userID).Scan(&unread); err != nil {
+		return 0, fmt.Errorf("failed to count unread notifications: %v", err)
+	}
+	return unread, nil
+}
+
+// MarkNotificationRead отмечает уведомление пользователя прочитанным; повторная отметка не ошибка
+func (db *StoragePostgresql) MarkNotificationRead(ctx context.Context, userID, notificationID int) error {
+	query := "UPDATE notifications SET read_at = COALESCE(read_at, CURRENT_TIMESTAMP) WHERE id = $1 AND user_id = $2"
+	result, err := db.Database.ExecContext(ctx, query, notificationID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to mark notification as read: %v", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
+
+// MarkAllNotificationsRead отмечает прочитанными все уведомления пользователя
+func (db *StoragePostgresql) MarkAllNotificationsRead(ctx context.Context, userID int) error {
+	query := "UPDATE notifications SET read_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND read_at IS NULL"
+	if _, err := db.Database.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to mark notifications as read: %v", err)
+	}
+	return nil
+}
+
+// insertPricePoint добавляет запись в историю цены объявления
+func insertPricePoint(ctx context.Context, tx *sql.Tx, adID int, price float64) error {
+	if _, err := tx.ExecContext(ctx, "INSERT INTO ad_price_history (ad_id, price) VALUES ($1, $2)", adID, price); err != nil {
+		return fmt.Errorf("failed to add price history: %v", err)
+	}
+	return nil
+}
+
+// insertAdImages сохраняет галерею по порядку, начиная с позиции 0
+func insertAdImages(ctx context.Context, tx *sql.Tx, adID int, urls []string) ([]models.AdImage, error) {
+	images := make([]models.AdImage, 0, len(urls))
+	for i, url := range urls {
+		img := models.AdImage{URL: url, Position: i}
+		query := "INSERT INTO ad_images (ad_id, url, position) VALUES ($1, $2, $3) RETURNING id"
+		if err := tx.QueryRowContext(ctx, query, adID, url, i).Scan(&img.ID); err != nil {
+			return nil, fmt.Errorf("failed to add ad image: %v", err)
+		}
+		images = append(images, img)
+	}
+	return images, nil
+}
+
+// syncAdCover записывает обложку в ads.image_url, которую читает лента
+func syncAdCover(ctx context.Context, tx *sql.Tx, adID int, url string) error {
+	result, err := tx.ExecContext(ctx, "UPDATE ads SET image_url = $1 WHERE id = $2", url, adID)
+	if err != nil {
+		return fmt.Errorf("failed to update ad cover: %v", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrAdNotFound
+	}
+	return nil
+}
+
+// lockEditableAd блокирует строку объявления до конца транзакции и возвращает его статус; архивное объявление менять нельзя
+func lockEditableAd(ctx context.Context, tx *sql.Tx, adID int) (string, error) {
+	var status string
+	if err := tx.QueryRowContext(ctx, "SELECT status FROM ads WHERE id = $1 FOR UPDATE", adID).Scan(&status); err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrAdNotFound
+		}
+		return "", fmt.Errorf("failed to lock ad: %v", err)
+	}
+	if status == models.AdStatusArchived {
+		return "", ErrAdStatusConflict
+	}
+	return status, nil
+}
+
+// requeueAfterContentChange отправляет объявление со статусом status, прочитанным под блокировкой, на повторную проверку,
+// если правка галереи этого требует
+func requeueAfterContentChange(ctx context.Context, tx *sql.Tx, adID int, status string) error {
+	next := models.StatusAfterContentChange(status)
+	if next == status {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE ads SET status = $1, rejection_reason = NULL WHERE id = $2", next, adID); err != nil {
+		return fmt.Errorf("failed to send ad to review: %v", err)
+	}
+	return nil
+}
+
+// adImageIDs возвращает ID изображений галереи по порядку
+func adImageIDs(ctx context.Context, tx *sql.Tx, adID int) ([]int, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT id FROM ad_images WHERE ad_id = $1 ORDER BY position", adID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ad images: %v", err)
+	}
+	defer rows.Close()
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan ad image: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get ad images: %v", err)
+	}
+	return ids, nil
+}
+
+// syncAdCoverFromImages делает обложкой изображение на позиции 0
+func syncAdCoverFromImages(ctx context.Context, tx *sql.Tx, adID int) error {
+	var url string
+	err := tx.QueryRowContext(ctx, "SELECT url FROM ad_images WHERE ad_id = $1 ORDER BY position LIMIT 1", adID).Scan(&url)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get ad cover: %v", err)
+	}
+	return syncAdCover(ctx, tx, adID, url)
+}
+
+// coverURL возвращает обложку галереи или пустую строку
+func coverURL(urls []string) string {
+	if len(urls) == 0 {
+		return ""
+	}
+	return urls[0]
+}
+
+// CreateRefreshToken сохраняет хеш нового refresh-токена
+func (db *StoragePostgresql) CreateRefreshToken(ctx context.Context, userID int, tokenHash, familyID string, expiresAt time.Time) error {
+	query := "INSERT INTO refresh_tokens (token_hash, family_id, user_id, expires_at) VALUES ($1, $2, $3, $4)"
+	if _, err := db.Database.ExecContext(ctx, query, tokenHash, familyID, userID, expiresAt.UTC()); err != nil {
+		return fmt.Errorf("failed to create refresh token: %v", err)
+	}
+	return nil
+}
+
+// UseRefreshToken атомарно погашает refresh-токен и возвращает его владельца и семейство.
+// Повторное предъявление погашенного токена отзывает все семейство.
+func (db *StoragePostgresql) UseRefreshToken(ctx context.Context, tokenHash string) (int, string, error) {
+	var userID int
+	var familyID string
+	query := `
+        UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP
+        WHERE token_hash = $1 AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+        RETURNING user_id, family_id`
+	err := db.Database.QueryRowContext(ctx, query, tokenHash).Scan(&userID, &familyID)
+	if err == nil {
+		return userID, familyID, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, "", fmt.Errorf("failed to use refresh token: %v", err)
+	}
+
+	var revoked bool
+	query = "SELECT revoked_at IS NOT NULL, user_id FROM refresh_tokens WHERE token_hash = $1"
+	err = db.Database.QueryRowContext(ctx, query, tokenHash).Scan(&revoked, &userID)
+	if err == sql.ErrNoRows || (err == nil && !revoked) {
+		return 0, "", ErrTokenNotFound
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to check refresh token: %v", err)
+	}
+	if err := db.RevokeRefreshTokenFamily(ctx, userID, tokenHash); err != nil {
+		return 0, "", err
+	}
+	return 0, "", ErrTokenReused
+}
+
+// RevokeRefreshTokenFamily отзывает все refresh-токены из семейства указанного токена.
+// Токен другого пользователя игнорируется, как и неизвестный
+func (db *StoragePostgresql) RevokeRefreshTokenFamily(ctx context.Context, userID int, tokenHash string) error {
+	query := `
+        UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP
+        WHERE revoked_at IS NULL
+          AND family_id = (SELECT family_id FROM refresh_tokens WHERE token_hash = $1 AND user_id = $2)`
+	if _, err := db.Database.ExecContext(ctx, query, tokenHash, userID); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %v", err)
+	}
+	return nil
+}
+
+// RevokeAccessToken заносит jti access-токена в denylist до истечения его срока
+func (db *StoragePostgresql) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	// просроченные записи больше не нужны: такие токены отклоняются по exp, поэтому удаляются тем же запросом
+	query := `
+        WITH expired AS (DELETE FROM revoked_tokens WHERE expires_at < CURRENT_TIMESTAMP)
+        INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2)
+        ON CONFLICT (jti) DO NOTHING`
+	if _, err := db.Database.ExecContext(ctx, query, jti, expiresAt.UTC()); err != nil {
+		return fmt.Errorf("failed to revoke access token: %v", err)
+	}
+	return nil
+}
+
+// IsAccessTokenRevoked проверяет, есть ли jti в denylist
+func (db *StoragePostgresql) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	var exists bool
+	query := "SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)"
+	if err := db.Database.QueryRowContext(ctx, query, jti).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check revoked token: %v", err)
 	}
 	return exists, nil
 }
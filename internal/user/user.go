@@ -4,16 +4,24 @@ import "time"
 
 // User - модель пользователя
 type User struct {
-	ID        string    `json:"id"`
+	ID        int       `json:"id"`
 	Login     string    `json:"login"`
 	Password  string    `json:"-"`
+	Role      string    `json:"role"`
 	CreatedAt time.Time `json:"created_at"`
+	// BannedAt заполнен у заблокированных пользователей
+	BannedAt *time.Time `json:"banned_at,omitempty"`
+}
+
+// Banned сообщает, что пользователь заблокирован
+func (u User) Banned() bool {
+	return u.BannedAt != nil
 }
 
 // Ad - модель объявления
 type Ad struct {
-	ID        string    `json:"id"`
-	UserID    string    `json:"user_id"`   // Связь с автором
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`   // Связь с автором
 	Title     string    `json:"title"`     // Ограничение: 100 символов
 	Text      string    `json:"text"`      // Ограничение: 1000 символов
 	ImageURL  string    `json:"image_url"` // Просто строка с URL